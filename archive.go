@@ -0,0 +1,192 @@
+package shp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// archiveLayer is a shapefile that was found among the members of an archive.
+type archiveLayer struct {
+	// name is the path of the .shp file in the archive.
+	name string
+	// files maps the lower-case extensions of all files that belong to the
+	// layer to their index in the list of archive members.
+	files map[string]int
+}
+
+// findLayers groups the archive members called names into shapefile layers.
+// Extensions are compared case-insensitively, so that ROADS.SHP and roads.dbf
+// belong to the same layer. Hidden files and resource forks, such as the
+// copies in a __MACOSX directory, are ignored. The layers are returned in the
+// order of their .shp files.
+func findLayers(names []string) []archiveLayer {
+	var layers []*archiveLayer
+	byBase := make(map[string]*archiveLayer)
+	var sidecars []int
+	for i, name := range names {
+		if ignoreArchiveMember(name) {
+			continue
+		}
+		ext := path.Ext(name)
+		if strings.ToLower(ext) != ".shp" {
+			sidecars = append(sidecars, i)
+			continue
+		}
+		base := strings.ToLower(strings.TrimSuffix(name, ext))
+		if _, ok := byBase[base]; ok {
+			continue // same shapefile with differently cased extension
+		}
+		l := &archiveLayer{name: name, files: map[string]int{".shp": i}}
+		byBase[base] = l
+		layers = append(layers, l)
+	}
+	for _, i := range sidecars {
+		ext := path.Ext(names[i])
+		l, ok := byBase[strings.ToLower(strings.TrimSuffix(names[i], ext))]
+		if !ok {
+			continue
+		}
+		if _, ok := l.files[strings.ToLower(ext)]; !ok {
+			l.files[strings.ToLower(ext)] = i
+		}
+	}
+	r := make([]archiveLayer, len(layers))
+	for i, l := range layers {
+		r[i] = *l
+	}
+	return r
+}
+
+// ignoreArchiveMember reports whether the archive member called name is a
+// directory, a hidden file or lies in a hidden directory. This includes
+// __MACOSX directories and the ._ files that hold Mac OS resource forks.
+func ignoreArchiveMember(name string) bool {
+	if strings.HasSuffix(name, "/") {
+		return true
+	}
+	for _, c := range strings.Split(name, "/") {
+		if c == "__MACOSX" || (strings.HasPrefix(c, ".") && c != "." && c != "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// singleLayer returns the only layer in layers.
+func singleLayer(layers []archiveLayer) (archiveLayer, error) {
+	if len(layers) == 0 {
+		return archiveLayer{}, fmt.Errorf("%w: archive does not contain a .shp file", ErrFileNotFound)
+	}
+	if len(layers) > 1 {
+		return archiveLayer{}, ErrMultipleShapes
+	}
+	return layers[0], nil
+}
+
+// layerByName returns the layer whose .shp file is called name. If there is no
+// exact match, the name is compared case-insensitively.
+func layerByName(layers []archiveLayer, name string) (archiveLayer, error) {
+	for _, l := range layers {
+		if l.name == name {
+			return l, nil
+		}
+	}
+	for _, l := range layers {
+		if strings.EqualFold(l.name, name) {
+			return l, nil
+		}
+	}
+	return archiveLayer{}, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+}
+
+// layerOpener returns a function that opens the file of layer l with the
+// given extension by passing its member index to open.
+func layerOpener(l archiveLayer, open func(i int) (readSeekCloser, error)) func(ext string) (readSeekCloser, error) {
+	return func(ext string) (readSeekCloser, error) {
+		i, ok := l.files[strings.ToLower(ext)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s file for %s", ErrFileNotFound, ext, l.name)
+		}
+		return open(i)
+	}
+}
+
+// readerFromLayer returns a Reader for layer l. The files of the layer are
+// opened by passing their member index to open.
+func readerFromLayer(l archiveLayer, open func(i int) (readSeekCloser, error), opts []ReadOption) (*Reader, error) {
+	opener := layerOpener(l, open)
+	shp, err := opener(".shp")
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{
+		filename:    strings.TrimSuffix(l.name, path.Ext(l.name)),
+		shp:         shp,
+		opener:      opener,
+		readOptions: newReadOptions(opts),
+	}
+	if err := r.readHeaders(); err != nil {
+		shp.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// maxInMemorySpill is the uncompressed size up to which compressed archive
+// members are inflated into memory instead of a temporary file.
+const maxInMemorySpill = 16 << 20
+
+// spill copies the size bytes from r into memory or, if they are too many or
+// size is negative because it is unknown, into a temporary file that is
+// removed again when it is closed. This makes archive members that can only be
+// read sequentially available for random access.
+func spill(r io.Reader, size int64) (readSeekCloser, error) {
+	if size >= 0 && size <= maxInMemorySpill {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return nopSeekCloser{bytes.NewReader(b)}, nil
+	}
+	tmp, err := ioutil.TempFile("", "go-shp-spill")
+	if err != nil {
+		return nil, err
+	}
+	sf := spillFile{tmp}
+	if _, err := io.Copy(tmp, r); err != nil {
+		sf.Close()
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		sf.Close()
+		return nil, err
+	}
+	return sf, nil
+}
+
+// nopSeekCloser adds a no-op Close method to an io.ReadSeeker.
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// spillFile is a temporary file that is removed when it is closed.
+type spillFile struct {
+	*os.File
+}
+
+func (f spillFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,271 @@
+package shp
+
+// These are the types of the parts of a MultiPatch.
+const (
+	PartTriangleStrip int32 = 0
+	PartTriangleFan   int32 = 1
+	PartOuterRing     int32 = 2
+	PartInnerRing     int32 = 3
+	PartFirstRing     int32 = 4
+	PartRing          int32 = 5
+)
+
+// PolygonOption configures how the constructors of polygons treat the rings
+// that they are given.
+type PolygonOption func(*polygonOptions)
+
+type polygonOptions struct {
+	closeRings  bool
+	orientRings bool
+}
+
+// CloseRings makes the constructors of polygons append the first point of a
+// ring to its end if the ring is not closed yet.
+func CloseRings() PolygonOption {
+	return func(o *polygonOptions) {
+		o.closeRings = true
+	}
+}
+
+// OrientRings makes the constructors of polygons reverse rings as needed so
+// that outer rings are clockwise and holes are counterclockwise, as the
+// specification requires. A ring is a hole if it lies within an odd number
+// of other rings.
+func OrientRings() PolygonOption {
+	return func(o *polygonOptions) {
+		o.orientRings = true
+	}
+}
+
+// NewPolygon returns a pointer to a new Polygon created from the given rings.
+// Empty rings are left out.
+func NewPolygon(rings [][]Point, opts ...PolygonOption) *Polygon {
+	p := &Polygon{}
+	for i, idx := range ringIndexes(rings, opts) {
+		if len(idx) > 0 {
+			p.Parts = append(p.Parts, int32(len(p.Points)))
+		}
+		for _, j := range idx {
+			p.Points = append(p.Points, rings[i][j])
+		}
+	}
+	p.Normalize()
+	return p
+}
+
+// NewMultiPoint returns a pointer to a new MultiPoint with the given points.
+func NewMultiPoint(points []Point) *MultiPoint {
+	p := &MultiPoint{Points: points}
+	p.Normalize()
+	return p
+}
+
+// NewPolyLineZ returns a pointer to a new PolyLineZ created from the given
+// parts, whose points provide the Z values and measures. Empty parts are
+// left out.
+//
+// The M of a PointZ is a real measure even if it is 0. Points without
+// measure must have M set to NoData, otherwise the PolyLineZ has measures
+// and is written with them.
+func NewPolyLineZ(parts [][]PointZ) *PolyLineZ {
+	p := &PolyLineZ{}
+	for _, part := range parts {
+		if len(part) > 0 {
+			p.Parts = append(p.Parts, int32(len(p.Points)))
+		}
+		for _, pt := range part {
+			p.Points = append(p.Points, Point{pt.X, pt.Y})
+			p.ZArray = append(p.ZArray, pt.Z)
+			p.MArray = append(p.MArray, pt.M)
+		}
+	}
+	p.Normalize()
+	return p
+}
+
+// NewPolygonZ returns a pointer to a new PolygonZ created from the given
+// rings like NewPolygon. Points without measure must have M set to NoData,
+// as for NewPolyLineZ.
+func NewPolygonZ(rings [][]PointZ, opts ...PolygonOption) *PolygonZ {
+	xy := make([][]Point, len(rings))
+	for i, ring := range rings {
+		for _, pt := range ring {
+			xy[i] = append(xy[i], Point{pt.X, pt.Y})
+		}
+	}
+	p := &PolygonZ{}
+	for i, idx := range ringIndexes(xy, opts) {
+		if len(idx) > 0 {
+			p.Parts = append(p.Parts, int32(len(p.Points)))
+		}
+		for _, j := range idx {
+			pt := rings[i][j]
+			p.Points = append(p.Points, Point{pt.X, pt.Y})
+			p.ZArray = append(p.ZArray, pt.Z)
+			p.MArray = append(p.MArray, pt.M)
+		}
+	}
+	p.Normalize()
+	return p
+}
+
+// NewMultiPointZ returns a pointer to a new MultiPointZ with the given
+// points. Points without measure must have M set to NoData, as for
+// NewPolyLineZ.
+func NewMultiPointZ(points []PointZ) *MultiPointZ {
+	p := &MultiPointZ{}
+	for _, pt := range points {
+		p.Points = append(p.Points, Point{pt.X, pt.Y})
+		p.ZArray = append(p.ZArray, pt.Z)
+		p.MArray = append(p.MArray, pt.M)
+	}
+	p.Normalize()
+	return p
+}
+
+// NewPolyLineM returns a pointer to a new PolyLineM created from the given
+// parts, whose points provide the measures. Empty parts are left out.
+func NewPolyLineM(parts [][]PointM) *PolyLineM {
+	p := &PolyLineM{}
+	for _, part := range parts {
+		if len(part) > 0 {
+			p.Parts = append(p.Parts, int32(len(p.Points)))
+		}
+		for _, pt := range part {
+			p.Points = append(p.Points, Point{pt.X, pt.Y})
+			p.MArray = append(p.MArray, pt.M)
+		}
+	}
+	p.Normalize()
+	return p
+}
+
+// NewPolygonM returns a pointer to a new PolygonM created from the given
+// rings like NewPolygon.
+func NewPolygonM(rings [][]PointM, opts ...PolygonOption) *PolygonM {
+	xy := make([][]Point, len(rings))
+	for i, ring := range rings {
+		for _, pt := range ring {
+			xy[i] = append(xy[i], Point{pt.X, pt.Y})
+		}
+	}
+	p := &PolygonM{}
+	for i, idx := range ringIndexes(xy, opts) {
+		if len(idx) > 0 {
+			p.Parts = append(p.Parts, int32(len(p.Points)))
+		}
+		for _, j := range idx {
+			pt := rings[i][j]
+			p.Points = append(p.Points, Point{pt.X, pt.Y})
+			p.MArray = append(p.MArray, pt.M)
+		}
+	}
+	p.Normalize()
+	return p
+}
+
+// NewMultiPointM returns a pointer to a new MultiPointM with the given
+// points.
+func NewMultiPointM(points []PointM) *MultiPointM {
+	p := &MultiPointM{}
+	for _, pt := range points {
+		p.Points = append(p.Points, Point{pt.X, pt.Y})
+		p.MArray = append(p.MArray, pt.M)
+	}
+	p.Normalize()
+	return p
+}
+
+// NewMultiPatch returns a pointer to a new MultiPatch created from the given
+// parts and their types, e.g. PartTriangleStrip or PartOuterRing. Empty parts
+// are left out. Points without measure must have M set to NoData, as for
+// NewPolyLineZ.
+func NewMultiPatch(parts [][]PointZ, partTypes []int32) *MultiPatch {
+	p := &MultiPatch{}
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		p.Parts = append(p.Parts, int32(len(p.Points)))
+		if i < len(partTypes) {
+			p.PartTypes = append(p.PartTypes, partTypes[i])
+		} else {
+			p.PartTypes = append(p.PartTypes, PartTriangleStrip)
+		}
+		for _, pt := range part {
+			p.Points = append(p.Points, Point{pt.X, pt.Y})
+			p.ZArray = append(p.ZArray, pt.Z)
+			p.MArray = append(p.MArray, pt.M)
+		}
+	}
+	p.Normalize()
+	return p
+}
+
+// ringIndexes returns for each of the rings the indexes of its points in the
+// order in which they are written according to opts.
+func ringIndexes(rings [][]Point, opts []PolygonOption) [][]int {
+	var o polygonOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	indexes := make([][]int, len(rings))
+	for i, ring := range rings {
+		reverse := false
+		if o.orientRings && len(ring) > 0 {
+			// outer rings are clockwise, which means that their signed
+			// area is negative
+			area := signedArea(ring)
+			if isHole(rings, i) {
+				reverse = area < 0
+			} else {
+				reverse = area > 0
+			}
+		}
+		for j := range ring {
+			if reverse {
+				j = len(ring) - 1 - j
+			}
+			indexes[i] = append(indexes[i], j)
+		}
+		if o.closeRings && len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			indexes[i] = append(indexes[i], indexes[i][0])
+		}
+	}
+	return indexes
+}
+
+// signedArea returns the area of ring, which is positive if the ring is
+// counterclockwise and negative if it is clockwise.
+func signedArea(ring []Point) float64 {
+	var a float64
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a / 2
+}
+
+// isHole reports whether the i-th ring lies within an odd number of the
+// other rings, which is tested with its first point.
+func isHole(rings [][]Point, i int) bool {
+	n := 0
+	for j, ring := range rings {
+		if j != i && pointInRing(rings[i][0], ring) {
+			n++
+		}
+	}
+	return n%2 == 1
+}
+
+// pointInRing reports whether p lies within ring using the even-odd rule.
+func pointInRing(p Point, ring []Point) bool {
+	in := false
+	for i, a := range ring {
+		b := ring[(i+1)%len(ring)]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,56 @@
+package shp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewPolygon(t *testing.T) {
+	outer := []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}} // counterclockwise
+	hole := []Point{{2, 2}, {2, 4}, {4, 4}, {4, 2}}      // clockwise
+
+	p := NewPolygon([][]Point{outer, hole})
+	if p.NumParts != 2 || p.NumPoints != 8 || !reflect.DeepEqual(p.Parts, []int32{0, 4}) {
+		t.Errorf("got %d parts %v and %d points", p.NumParts, p.Parts, p.NumPoints)
+	}
+	if want := (Box{0, 0, 10, 10}); p.Box != want {
+		t.Errorf("got box %v, want %v", p.Box, want)
+	}
+
+	p = NewPolygon([][]Point{outer, hole}, CloseRings(), OrientRings())
+	want := []Point{
+		{0, 10}, {10, 10}, {10, 0}, {0, 0}, {0, 10},
+		{4, 2}, {4, 4}, {2, 4}, {2, 2}, {4, 2},
+	}
+	if !reflect.DeepEqual(p.Points, want) || !reflect.DeepEqual(p.Parts, []int32{0, 5}) {
+		t.Errorf("got points %v in parts %v, want %v", p.Points, p.Parts, want)
+	}
+	if signedArea(p.Points[:5]) >= 0 || signedArea(p.Points[5:]) <= 0 {
+		t.Error("outer ring is not clockwise or hole is not counterclockwise")
+	}
+}
+
+func TestNewZMShapes(t *testing.T) {
+	l := NewPolyLineZ([][]PointZ{{{0, 0, 1, 5}, {1, 1, 2, 6}}, {}, {{3, -1, -4, 7}}})
+	if !reflect.DeepEqual(l.Parts, []int32{0, 2}) || l.NumPoints != 3 {
+		t.Errorf("got parts %v and %d points", l.Parts, l.NumPoints)
+	}
+	if l.Box != (Box{0, -1, 3, 1}) || l.ZRange != [2]float64{-4, 2} || l.MRange != [2]float64{5, 7} {
+		t.Errorf("got box %v, Z range %v and M range %v", l.Box, l.ZRange, l.MRange)
+	}
+
+	pm := NewPolygonM([][]PointM{{{0, 0, 1}, {0, 1, 2}, {1, 0, 3}}}, CloseRings())
+	if pm.NumPoints != 4 || !reflect.DeepEqual(pm.MArray, []float64{1, 2, 3, 1}) || pm.MRange != [2]float64{1, 3} {
+		t.Errorf("got %d points with measures %v in range %v", pm.NumPoints, pm.MArray, pm.MRange)
+	}
+
+	mp := NewMultiPointM([]PointM{{1, 1, 9}, {2, 3, 8}})
+	if mp.NumPoints != 2 || mp.Box != (Box{1, 1, 2, 3}) || mp.MRange != [2]float64{8, 9} {
+		t.Errorf("got %+v", mp)
+	}
+
+	patch := NewMultiPatch([][]PointZ{{{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 1, 1, 0}}, {{5, 5, 5, 0}}}, []int32{PartTriangleFan})
+	if !reflect.DeepEqual(patch.PartTypes, []int32{PartTriangleFan, PartTriangleStrip}) || patch.ZRange != [2]float64{0, 5} {
+		t.Errorf("got part types %v and Z range %v", patch.PartTypes, patch.ZRange)
+	}
+}
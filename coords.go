@@ -0,0 +1,278 @@
+package shp
+
+// Coord is a point with all coordinates that a shape can have. Z is 0 for
+// shapes without Z values and M is NoData for shapes without measures.
+type Coord struct {
+	X, Y, Z, M float64
+}
+
+// ShapeType returns NULL.
+func (n Null) ShapeType() ShapeType { return NULL }
+
+// PartCount returns 0 as the Null has no parts.
+func (n Null) PartCount() int { return 0 }
+
+// Part panics as the Null has no parts.
+func (n Null) Part(i int) []Coord {
+	start, end := partBounds(nil, 0, i)
+	return n.Coords()[start:end]
+}
+
+// Coords returns nil as the Null has no points.
+func (n Null) Coords() []Coord { return nil }
+
+// ShapeType returns POINT.
+func (p Point) ShapeType() ShapeType { return POINT }
+
+// PartCount returns 1 as the Point is its only part.
+func (p Point) PartCount() int { return 1 }
+
+// Part returns the Point as its only part. It panics if i is not 0.
+func (p Point) Part(i int) []Coord {
+	start, end := partBounds(nil, 1, i)
+	return p.Coords()[start:end]
+}
+
+// Coords returns the Point as a Coord.
+func (p Point) Coords() []Coord { return []Coord{{X: p.X, Y: p.Y, M: NoData}} }
+
+// ShapeType returns POLYLINE.
+func (p PolyLine) ShapeType() ShapeType { return POLYLINE }
+
+// PartCount returns the number of parts of the PolyLine. Points without
+// parts form a single part, like when the PolyLine is written.
+func (p PolyLine) PartCount() int { return partCount(p.Parts, len(p.Points)) }
+
+// Part returns the coordinates of the i-th part of the PolyLine. It panics
+// if i is out of range.
+func (p PolyLine) Part(i int) []Coord {
+	start, end := partBounds(p.Parts, len(p.Points), i)
+	return coords(p.Points, nil, nil, start, end)
+}
+
+// Coords returns the coordinates of all points of the PolyLine. Changing
+// them does not change the PolyLine.
+func (p PolyLine) Coords() []Coord { return coords(p.Points, nil, nil, 0, len(p.Points)) }
+
+// ShapeType returns POLYGON.
+func (p Polygon) ShapeType() ShapeType { return POLYGON }
+
+// PartCount returns the number of rings of the Polygon like PolyLine.PartCount.
+func (p Polygon) PartCount() int { return PolyLine(p).PartCount() }
+
+// Part returns the coordinates of the i-th ring of the Polygon. It panics if
+// i is out of range.
+func (p Polygon) Part(i int) []Coord { return PolyLine(p).Part(i) }
+
+// Coords returns the coordinates of all points of the Polygon.
+func (p Polygon) Coords() []Coord { return PolyLine(p).Coords() }
+
+// ShapeType returns MULTIPOINT.
+func (p MultiPoint) ShapeType() ShapeType { return MULTIPOINT }
+
+// PartCount returns 1 as all points of the MultiPoint form a single part,
+// or 0 if there are none.
+func (p MultiPoint) PartCount() int { return partCount(nil, len(p.Points)) }
+
+// Part returns the coordinates of all points of the MultiPoint as its only
+// part. It panics if i is out of range.
+func (p MultiPoint) Part(i int) []Coord {
+	start, end := partBounds(nil, len(p.Points), i)
+	return coords(p.Points, nil, nil, start, end)
+}
+
+// Coords returns the coordinates of all points of the MultiPoint.
+func (p MultiPoint) Coords() []Coord { return coords(p.Points, nil, nil, 0, len(p.Points)) }
+
+// ShapeType returns POINTZ.
+func (p PointZ) ShapeType() ShapeType { return POINTZ }
+
+// PartCount returns 1 as the PointZ is its only part.
+func (p PointZ) PartCount() int { return 1 }
+
+// Part returns the PointZ as its only part. It panics if i is not 0.
+func (p PointZ) Part(i int) []Coord {
+	start, end := partBounds(nil, 1, i)
+	return p.Coords()[start:end]
+}
+
+// Coords returns the PointZ as a Coord.
+func (p PointZ) Coords() []Coord { return []Coord{{p.X, p.Y, p.Z, p.M}} }
+
+// ShapeType returns POLYLINEZ.
+func (p PolyLineZ) ShapeType() ShapeType { return POLYLINEZ }
+
+// PartCount returns the number of parts of the PolyLineZ like
+// PolyLine.PartCount.
+func (p PolyLineZ) PartCount() int { return partCount(p.Parts, len(p.Points)) }
+
+// Part returns the coordinates of the i-th part of the PolyLineZ. It panics
+// if i is out of range.
+func (p PolyLineZ) Part(i int) []Coord {
+	start, end := partBounds(p.Parts, len(p.Points), i)
+	return coords(p.Points, p.ZArray, p.MArray, start, end)
+}
+
+// Coords returns the coordinates of all points of the PolyLineZ with their
+// Z values and measures. Changing them does not change the PolyLineZ.
+func (p PolyLineZ) Coords() []Coord {
+	return coords(p.Points, p.ZArray, p.MArray, 0, len(p.Points))
+}
+
+// ShapeType returns POLYGONZ.
+func (p PolygonZ) ShapeType() ShapeType { return POLYGONZ }
+
+// PartCount returns the number of rings of the PolygonZ like
+// PolyLine.PartCount.
+func (p PolygonZ) PartCount() int { return PolyLineZ(p).PartCount() }
+
+// Part returns the coordinates of the i-th ring of the PolygonZ. It panics
+// if i is out of range.
+func (p PolygonZ) Part(i int) []Coord { return PolyLineZ(p).Part(i) }
+
+// Coords returns the coordinates of all points of the PolygonZ.
+func (p PolygonZ) Coords() []Coord { return PolyLineZ(p).Coords() }
+
+// ShapeType returns MULTIPOINTZ.
+func (p MultiPointZ) ShapeType() ShapeType { return MULTIPOINTZ }
+
+// PartCount returns the number of parts of the MultiPointZ like
+// MultiPoint.PartCount.
+func (p MultiPointZ) PartCount() int { return partCount(nil, len(p.Points)) }
+
+// Part returns the coordinates of all points of the MultiPointZ as its only
+// part. It panics if i is out of range.
+func (p MultiPointZ) Part(i int) []Coord {
+	start, end := partBounds(nil, len(p.Points), i)
+	return coords(p.Points, p.ZArray, p.MArray, start, end)
+}
+
+// Coords returns the coordinates of all points of the MultiPointZ.
+func (p MultiPointZ) Coords() []Coord {
+	return coords(p.Points, p.ZArray, p.MArray, 0, len(p.Points))
+}
+
+// ShapeType returns POINTM.
+func (p PointM) ShapeType() ShapeType { return POINTM }
+
+// PartCount returns 1 as the PointM is its only part.
+func (p PointM) PartCount() int { return 1 }
+
+// Part returns the PointM as its only part. It panics if i is not 0.
+func (p PointM) Part(i int) []Coord {
+	start, end := partBounds(nil, 1, i)
+	return p.Coords()[start:end]
+}
+
+// Coords returns the PointM as a Coord.
+func (p PointM) Coords() []Coord { return []Coord{{X: p.X, Y: p.Y, M: p.M}} }
+
+// ShapeType returns POLYLINEM.
+func (p PolyLineM) ShapeType() ShapeType { return POLYLINEM }
+
+// PartCount returns the number of parts of the PolyLineM like
+// PolyLine.PartCount.
+func (p PolyLineM) PartCount() int { return partCount(p.Parts, len(p.Points)) }
+
+// Part returns the coordinates of the i-th part of the PolyLineM. It panics
+// if i is out of range.
+func (p PolyLineM) Part(i int) []Coord {
+	start, end := partBounds(p.Parts, len(p.Points), i)
+	return coords(p.Points, nil, p.MArray, start, end)
+}
+
+// Coords returns the coordinates of all points of the PolyLineM with their
+// measures. Changing them does not change the PolyLineM.
+func (p PolyLineM) Coords() []Coord {
+	return coords(p.Points, nil, p.MArray, 0, len(p.Points))
+}
+
+// ShapeType returns POLYGONM.
+func (p PolygonM) ShapeType() ShapeType { return POLYGONM }
+
+// PartCount returns the number of rings of the PolygonM like
+// PolyLine.PartCount.
+func (p PolygonM) PartCount() int { return PolyLineM(p).PartCount() }
+
+// Part returns the coordinates of the i-th ring of the PolygonM. It panics
+// if i is out of range.
+func (p PolygonM) Part(i int) []Coord { return PolyLineM(p).Part(i) }
+
+// Coords returns the coordinates of all points of the PolygonM.
+func (p PolygonM) Coords() []Coord { return PolyLineM(p).Coords() }
+
+// ShapeType returns MULTIPOINTM.
+func (p MultiPointM) ShapeType() ShapeType { return MULTIPOINTM }
+
+// PartCount returns the number of parts of the MultiPointM like
+// MultiPoint.PartCount.
+func (p MultiPointM) PartCount() int { return partCount(nil, len(p.Points)) }
+
+// Part returns the coordinates of all points of the MultiPointM as its only
+// part. It panics if i is out of range.
+func (p MultiPointM) Part(i int) []Coord {
+	start, end := partBounds(nil, len(p.Points), i)
+	return coords(p.Points, nil, p.MArray, start, end)
+}
+
+// Coords returns the coordinates of all points of the MultiPointM.
+func (p MultiPointM) Coords() []Coord {
+	return coords(p.Points, nil, p.MArray, 0, len(p.Points))
+}
+
+// ShapeType returns MULTIPATCH.
+func (p MultiPatch) ShapeType() ShapeType { return MULTIPATCH }
+
+// PartCount returns the number of parts of the MultiPatch like
+// PolyLine.PartCount. Their types are given by PartTypes.
+func (p MultiPatch) PartCount() int { return partCount(p.Parts, len(p.Points)) }
+
+// Part returns the coordinates of the i-th part of the MultiPatch. It panics
+// if i is out of range.
+func (p MultiPatch) Part(i int) []Coord {
+	start, end := partBounds(p.Parts, len(p.Points), i)
+	return coords(p.Points, p.ZArray, p.MArray, start, end)
+}
+
+// Coords returns the coordinates of all points of the MultiPatch.
+func (p MultiPatch) Coords() []Coord {
+	return coords(p.Points, p.ZArray, p.MArray, 0, len(p.Points))
+}
+
+// partCount returns the number of parts of a shape with numPoints points
+// that start at the indexes in parts.
+func partCount(parts []int32, numPoints int) int {
+	return len(normalizeParts(parts, numPoints))
+}
+
+// partBounds returns the indexes of the first point of the i-th part and of
+// the point after its last point. It panics if i is out of range.
+func partBounds(parts []int32, numPoints, i int) (start, end int) {
+	parts = normalizeParts(parts, numPoints)
+	start, end = int(parts[i]), numPoints
+	if i+1 < len(parts) {
+		end = int(parts[i+1])
+	}
+	return start, end
+}
+
+// coords returns the points from start to end as coordinates, whose Z values
+// and measures are taken from z and m. Missing Z values are 0 and missing
+// measures are NoData. Without points, nil is returned.
+func coords(points []Point, z, m []float64, start, end int) []Coord {
+	if start == end {
+		return nil
+	}
+	c := make([]Coord, end-start)
+	for i := range c {
+		j := start + i
+		c[i] = Coord{X: points[j].X, Y: points[j].Y, M: NoData}
+		if j < len(z) {
+			c[i].Z = z[j]
+		}
+		if j < len(m) {
+			c[i].M = m[j]
+		}
+	}
+	return c
+}
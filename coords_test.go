@@ -0,0 +1,78 @@
+package shp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoords(t *testing.T) {
+	tests := []struct {
+		shape Shape
+		typ   ShapeType
+		parts [][]Coord
+	}{
+		{&Null{}, NULL, nil},
+		{&Point{1, 2}, POINT, [][]Coord{{{1, 2, 0, NoData}}}},
+		{&PointZ{1, 2, 3, 4}, POINTZ, [][]Coord{{{1, 2, 3, 4}}}},
+		{&PointM{1, 2, 4}, POINTM, [][]Coord{{{1, 2, 0, 4}}}},
+		{
+			NewPolygon([][]Point{{{0, 0}, {0, 1}, {1, 0}}, {{5, 5}}}),
+			POLYGON,
+			[][]Coord{{{0, 0, 0, NoData}, {0, 1, 0, NoData}, {1, 0, 0, NoData}}, {{5, 5, 0, NoData}}},
+		},
+		{
+			&PolyLine{Points: []Point{{0, 0}, {1, 1}}}, // without parts
+			POLYLINE,
+			[][]Coord{{{0, 0, 0, NoData}, {1, 1, 0, NoData}}},
+		},
+		{
+			NewPolyLineZ([][]PointZ{{{0, 0, 1, 2}}, {{1, 1, 3, NoData}, {2, 2, 4, 5}}}),
+			POLYLINEZ,
+			[][]Coord{{{0, 0, 1, 2}}, {{1, 1, 3, NoData}, {2, 2, 4, 5}}},
+		},
+		{
+			&PolygonM{Parts: []int32{0}, Points: []Point{{0, 0}, {1, 1}}, MArray: []float64{7}},
+			POLYGONM,
+			[][]Coord{{{0, 0, 0, 7}, {1, 1, 0, NoData}}},
+		},
+		{
+			NewMultiPointZ([]PointZ{{0, 0, 1, 2}, {1, 1, 3, 4}}),
+			MULTIPOINTZ,
+			[][]Coord{{{0, 0, 1, 2}, {1, 1, 3, 4}}},
+		},
+		{&MultiPointM{}, MULTIPOINTM, nil},
+		{
+			NewMultiPatch([][]PointZ{{{0, 0, 1, NoData}}, {{1, 1, 2, NoData}}}, []int32{PartOuterRing, PartInnerRing}),
+			MULTIPATCH,
+			[][]Coord{{{0, 0, 1, NoData}}, {{1, 1, 2, NoData}}},
+		},
+	}
+	for _, test := range tests {
+		if got := test.shape.ShapeType(); got != test.typ {
+			t.Errorf("%T: got shape type %v, want %v", test.shape, got, test.typ)
+		}
+		if got := test.shape.PartCount(); got != len(test.parts) {
+			t.Errorf("%T: got %d parts, want %d", test.shape, got, len(test.parts))
+			continue
+		}
+		var all []Coord
+		for i, want := range test.parts {
+			if got := test.shape.Part(i); !reflect.DeepEqual(got, want) {
+				t.Errorf("%T: got part %d %v, want %v", test.shape, i, got, want)
+			}
+			all = append(all, want...)
+		}
+		if got := test.shape.Coords(); !reflect.DeepEqual(got, all) {
+			t.Errorf("%T: got coordinates %v, want %v", test.shape, got, all)
+		}
+	}
+}
+
+func TestPartOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Part did not panic")
+		}
+	}()
+	NewPolyLineZ([][]PointZ{{{0, 0, 1, 2}}}).Part(1)
+}
@@ -0,0 +1,236 @@
+package shp
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Dataset is a collection of shapefiles, the layers, that are stored together
+// in a ZIP archive or a directory. The archive is opened only once and shared
+// by all layers that are opened from the Dataset.
+type Dataset struct {
+	layers []archiveLayer
+	info   []LayerInfo
+
+	// members holds the paths of all files in the Dataset and sizes holds
+	// their sizes in bytes.
+	members []string
+	sizes   []int64
+
+	// open opens the i-th member for random access, stream opens it for
+	// sequential reading.
+	open   func(i int) (readSeekCloser, error)
+	stream func(i int) (io.ReadCloser, error)
+
+	closer io.Closer
+}
+
+// LayerInfo describes a layer of a Dataset.
+type LayerInfo struct {
+	// Name is the path of the .shp file without extension, relative to the
+	// root of the Dataset and with forward slashes.
+	Name         string
+	GeometryType ShapeType
+	// NumFeatures is the number of records in the shapefile, which is
+	// taken from the index or the DBF table. It is -1 if the layer has
+	// neither.
+	NumFeatures int
+	Fields      []Field
+	BBox        Box
+	// Err is the error that occurred when reading the headers of the layer,
+	// in which case the other fields except Name may be incomplete.
+	Err error
+}
+
+// OpenDataset opens the ZIP archive or the directory at p as a Dataset. The
+// directory is searched recursively for shapefiles.
+func OpenDataset(p string) (*Dataset, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return openDirDataset(p)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewZipDataset(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.closer = f
+	return d, nil
+}
+
+// NewZipDataset returns a Dataset for the ZIP archive of the given size that
+// is read from ra. Closing the Dataset does not close ra.
+func NewZipDataset(ra io.ReaderAt, size int64) (*Dataset, error) {
+	z, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dataset{
+		open: func(i int) (readSeekCloser, error) {
+			return openSeekableFromZIP(ra, z.File[i])
+		},
+		stream: func(i int) (io.ReadCloser, error) {
+			return z.File[i].Open()
+		},
+	}
+	for _, f := range z.File {
+		d.members = append(d.members, f.Name)
+		d.sizes = append(d.sizes, int64(f.UncompressedSize64))
+	}
+	d.init()
+	return d, nil
+}
+
+func openDirDataset(dir string) (*Dataset, error) {
+	d := &Dataset{}
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		d.members = append(d.members, filepath.ToSlash(rel))
+		d.sizes = append(d.sizes, fi.Size())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.open = func(i int) (readSeekCloser, error) {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(d.members[i])))
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	d.stream = func(i int) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(d.members[i])))
+	}
+	d.init()
+	return d, nil
+}
+
+// init finds the layers of d and reads their headers. A layer whose headers
+// cannot be read is kept with the error in its LayerInfo.
+func (d *Dataset) init() {
+	d.layers = findLayers(d.members)
+	d.info = make([]LayerInfo, len(d.layers))
+	for i, l := range d.layers {
+		d.info[i] = d.layerInfo(l)
+	}
+}
+
+// layerInfo reads the headers of the SHP file and the DBF table of l, but
+// none of their records. The number of features is derived from the size of
+// the index file if there is one, otherwise from the DBF header.
+func (d *Dataset) layerInfo(l archiveLayer) LayerInfo {
+	info := LayerInfo{
+		Name:        strings.TrimSuffix(l.name, path.Ext(l.name)),
+		NumFeatures: -1,
+	}
+	sr, err := d.openSequential(l, nil)
+	if err != nil {
+		info.Err = err
+		return info
+	}
+	defer sr.Close()
+	info.GeometryType = sr.geometryType
+	info.BBox = sr.bbox
+	info.Fields = sr.Fields()
+	if i, ok := l.files[".shx"]; ok {
+		info.NumFeatures = int((d.sizes[i] - 100) / 8)
+	} else if h, ok := DBFHeader(sr); ok {
+		info.NumFeatures = h.NumRecords
+	}
+	info.Err = sr.Err()
+	return info
+}
+
+// Layers returns the descriptions of all layers in the Dataset. Layers whose
+// headers cannot be read are included with their Err set.
+func (d *Dataset) Layers() []LayerInfo {
+	return d.info
+}
+
+// layer returns the layer called name. The name is the path of the .shp file
+// with or without extension and is compared case-insensitively if there is no
+// exact match.
+func (d *Dataset) layer(name string) (archiveLayer, error) {
+	if strings.ToLower(path.Ext(name)) != ".shp" {
+		name += ".shp"
+	}
+	return layerByName(d.layers, name)
+}
+
+// Open opens the layer called name for random access. The name can be given
+// with or without the .shp extension.
+func (d *Dataset) Open(name string, opts ...ReadOption) (*Reader, error) {
+	l, err := d.layer(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.reader(l, opts)
+}
+
+func (d *Dataset) reader(l archiveLayer, opts []ReadOption) (*Reader, error) {
+	return readerFromLayer(l, d.open, opts)
+}
+
+// OpenSequential opens the layer called name for sequential reading. The name
+// can be given with or without the .shp extension.
+func (d *Dataset) OpenSequential(name string, opts ...ReadOption) (SequentialReader, error) {
+	l, err := d.layer(name)
+	if err != nil {
+		return nil, err
+	}
+	sr, err := d.openSequential(l, opts)
+	if err != nil {
+		return nil, err
+	}
+	sr.openMemo(layerOpener(l, d.open))
+	return sr, nil
+}
+
+// openSequential streams the SHP file and the DBF table of l, of which only
+// the headers have been read when it returns. The memo file is not opened.
+func (d *Dataset) openSequential(l archiveLayer, opts []ReadOption) (*seqReader, error) {
+	shp, err := d.stream(l.files[".shp"])
+	if err != nil {
+		return nil, err
+	}
+	var dbf io.ReadCloser
+	if i, ok := l.files[".dbf"]; ok {
+		if dbf, err = d.stream(i); err != nil {
+			shp.Close()
+			return nil, err
+		}
+	}
+	sr := &seqReader{shp: shp, readOptions: newReadOptions(opts)}
+	sr.readHeaders(dbf)
+	return sr, nil
+}
+
+// Close closes the Dataset. Layers that have been opened from the Dataset
+// must be closed before.
+func (d *Dataset) Close() error {
+	if d.closer != nil {
+		return d.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package shp
+
+import (
+	"archive/zip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testDatasetLayers(t *testing.T, d *Dataset, prefixes []string) {
+	layers := d.Layers()
+	if len(layers) != len(prefixes) {
+		t.Fatalf("got %d layers, want %d", len(layers), len(prefixes))
+	}
+	for _, prefix := range prefixes {
+		name := filepath.Base(prefix)
+		var info *LayerInfo
+		for i := range layers {
+			if layers[i].Name == name {
+				info = &layers[i]
+			}
+		}
+		if info == nil {
+			t.Errorf("layer %s not found", name)
+			continue
+		}
+		r, err := Open(prefix + ".shp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.GeometryType != r.GeometryType || info.BBox != r.BBox() {
+			t.Errorf("%s: got %v %v, want %v %v", name, info.GeometryType, info.BBox, r.GeometryType, r.BBox())
+		}
+		if len(info.Fields) != len(r.Fields()) {
+			t.Errorf("%s: got %d fields, want %d", name, len(info.Fields), len(r.Fields()))
+		}
+		r.Close()
+		if info.NumFeatures != dataForReadTests[prefix].count {
+			t.Errorf("%s: got %d features, want %d", name, info.NumFeatures, dataForReadTests[prefix].count)
+		}
+
+		testshapeIdentity(t, prefix, func(prefix string, t *testing.T) (shapes []Shape) {
+			r, err := d.Open(strings.ToUpper(name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			for r.Next() {
+				_, s := r.Shape()
+				shapes = append(shapes, s)
+			}
+			return shapes
+		})
+		testshapeIdentity(t, prefix, func(prefix string, t *testing.T) (shapes []Shape) {
+			sr, err := d.OpenSequential(name + ".shp")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sr.Close()
+			for sr.Next() {
+				_, s := sr.Shape()
+				shapes = append(shapes, s)
+			}
+			if sr.Err() != nil {
+				t.Error(sr.Err())
+			}
+			return shapes
+		})
+	}
+	if _, err := d.Open("missing"); err == nil {
+		t.Error("opened missing layer without error")
+	}
+}
+
+func TestZipDataset(t *testing.T) {
+	prefixes := []string{"test_files/point", "test_files/polygon", "test_files/multipatch"}
+	dir, err := ioutil.TempDir("", "go-shp-test")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	zipName := filepath.Join(dir, "dataset.zip")
+	w, err := os.Create(zipName)
+	if err != nil {
+		t.Fatalf("Could not create temporary zip file: %v", err)
+	}
+	zw := zip.NewWriter(w)
+	for _, prefix := range prefixes {
+		for _, suffix := range []string{".shp", ".shx", ".dbf"} {
+			compressFileToZIP(zw, prefix+suffix, filepath.Base(prefix)+suffix, zip.Deflate, t)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Could not close the written zip: %v", err)
+	}
+	w.Close()
+
+	d, err := OpenDataset(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	testDatasetLayers(t, d, prefixes)
+}
+
+func TestDirDataset(t *testing.T) {
+	var prefixes []string
+	for prefix := range dataForReadTests {
+		prefixes = append(prefixes, prefix)
+	}
+	d, err := OpenDataset("test_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	testDatasetLayers(t, d, prefixes)
+}
+
+func TestDatasetBadLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-test")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	zipName := filepath.Join(dir, "dataset.zip")
+	w, err := os.Create(zipName)
+	if err != nil {
+		t.Fatalf("Could not create temporary zip file: %v", err)
+	}
+	zw := zip.NewWriter(w)
+	for _, suffix := range []string{".shp", ".shx", ".dbf"} {
+		compressFileToZIP(zw, "test_files/point"+suffix, "point"+suffix, zip.Deflate, t)
+	}
+	bad, err := zw.Create("broken.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.Write(make([]byte, 20))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Could not close the written zip: %v", err)
+	}
+	w.Close()
+
+	d, err := OpenDataset(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, info := range d.Layers() {
+		switch info.Name {
+		case "point":
+			if info.Err != nil || info.NumFeatures != dataForReadTests["test_files/point"].count {
+				t.Errorf("point: got %d features with error %v", info.NumFeatures, info.Err)
+			}
+		case "broken":
+			if !errors.Is(info.Err, ErrTruncated) || info.NumFeatures != -1 {
+				t.Errorf("broken: got %d features with error %v, want ErrTruncated", info.NumFeatures, info.Err)
+			}
+		default:
+			t.Errorf("unexpected layer %s", info.Name)
+		}
+	}
+	if len(d.Layers()) != 2 {
+		t.Errorf("got %d layers, want 2", len(d.Layers()))
+	}
+}
@@ -0,0 +1,328 @@
+package dbf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonas-p/go-shp/internal/tempfile"
+)
+
+// AlterOp is an operation that changes the fields of a DBF table. The
+// operations are created by AddField, DropField, RenameField and ChangeField
+// and passed to Alter.
+type AlterOp struct {
+	apply func(s *schema) error
+}
+
+// column is a field of an altered table. src is the index of the field in the
+// original table from which the values are taken, or -1 for new fields.
+type column struct {
+	Field
+	src int
+}
+
+// schema holds the fields of a table that is being altered.
+type schema struct {
+	columns []column
+}
+
+// index returns the index of the column called name. Names are compared
+// case-insensitively.
+func (s *schema) index(name string) (int, error) {
+	for i, c := range s.columns {
+		if strings.EqualFold(c.String(), name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("No field %s in DBF table", name)
+}
+
+// checkName returns an error if there is a column called name other than the
+// one at index skip.
+func (s *schema) checkName(name string, skip int) error {
+	if i, err := s.index(name); err == nil && i != skip {
+		return fmt.Errorf("Duplicate field name %s", name)
+	}
+	return nil
+}
+
+// AddField adds field f to the end of the table. Its values are blank.
+func AddField(f Field) AlterOp {
+	return AlterOp{func(s *schema) error {
+		if err := checkName(f); err != nil {
+			return err
+		}
+		if err := s.checkName(f.String(), -1); err != nil {
+			return err
+		}
+		s.columns = append(s.columns, column{f, -1})
+		return nil
+	}}
+}
+
+// DropField removes the field called name and its values from the table.
+func DropField(name string) AlterOp {
+	return AlterOp{func(s *schema) error {
+		i, err := s.index(name)
+		if err != nil {
+			return err
+		}
+		s.columns = append(s.columns[:i], s.columns[i+1:]...)
+		return nil
+	}}
+}
+
+// RenameField changes the name of the field called name to newName.
+func RenameField(name, newName string) AlterOp {
+	return AlterOp{func(s *schema) error {
+		i, err := s.index(name)
+		if err != nil {
+			return err
+		}
+		f := s.columns[i].Field
+		f.Name = [11]byte{}
+		copy(f.Name[:], newName)
+		if err := checkName(f); err != nil {
+			return err
+		}
+		if err := s.checkName(newName, i); err != nil {
+			return err
+		}
+		s.columns[i].Field = f
+		return nil
+	}}
+}
+
+// ChangeField replaces the field called name with f, which can have a
+// different name, type, size or precision. The values are converted to suit
+// f, see Alter.
+func ChangeField(name string, f Field) AlterOp {
+	return AlterOp{func(s *schema) error {
+		i, err := s.index(name)
+		if err != nil {
+			return err
+		}
+		if err := checkName(f); err != nil {
+			return err
+		}
+		if err := s.checkName(f.String(), i); err != nil {
+			return err
+		}
+		s.columns[i].Field = f
+		return nil
+	}}
+}
+
+// ConversionError describes a value that could not be converted to the new
+// type or size of its field when a table was altered.
+type ConversionError struct {
+	Row   int
+	Field string
+	Err   error
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("DBF row %d, field %s: %v", e.Row, e.Field, e.Err)
+}
+
+// ConversionErrors is returned by Alter if values could not be converted.
+type ConversionErrors []*ConversionError
+
+func (e ConversionErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more conversion errors)", e[0], len(e)-1)
+}
+
+// Alter applies ops in the given order to the fields of the DBF file at
+// filename and rewrites the table, migrating the values of all records. The
+// table is written to a temporary file first, which then replaces the
+// original file.
+//
+// When a field is changed, its values are converted to the new type and
+// size where possible, e.g. from text to numbers or the other way round.
+// Values that cannot be converted, or no longer fit into the field, are left
+// blank and reported as ConversionErrors after the table has been written.
+// Memo fields can only be renamed or dropped; the memo file is not changed.
+func Alter(filename string, ops ...AlterOp) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	s := &schema{}
+	for i, field := range r.fields {
+		s.columns = append(s.columns, column{field, i})
+	}
+	for _, op := range ops {
+		if op.apply == nil {
+			return fmt.Errorf("Invalid AlterOp")
+		}
+		if err := op.apply(s); err != nil {
+			return err
+		}
+	}
+	fields := make([]Field, len(s.columns))
+	for i, c := range s.columns {
+		fields[i] = c.Field
+		if c.src >= 0 && (c.Fieldtype == 'M') != (r.fields[c.src].Fieldtype == 'M') {
+			return fmt.Errorf("Cannot convert field %s from type %c to %c", c, r.fields[c.src].Fieldtype, c.Fieldtype)
+		}
+	}
+
+	tmp, err := tempfile.Create(filename)
+	if err != nil {
+		return err
+	}
+	convErrs, err := alter(tmp, r, s.columns, fields)
+	if err != nil {
+		tmp.Discard()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		tmp.Discard()
+		return err
+	}
+	f.Close()
+	if err := tmp.Replace(); err != nil {
+		return err
+	}
+	if len(convErrs) > 0 {
+		return convErrs
+	}
+	return nil
+}
+
+// alter writes the records read from r with the given columns to the new
+// table tmp.
+func alter(tmp io.WriteSeeker, r *Reader, columns []column, fields []Field) (ConversionErrors, error) {
+	// keep the variant, unless the new fields require a newer one
+	version := r.header.Version
+	switch version {
+	case DBase3, DBase3Memo:
+		var err error
+		if version, err = defaultVersion(fields); err != nil {
+			return nil, err
+		}
+	case DBase7, DBase7Memo:
+		version = DBase7
+		if hasMemo(fields) {
+			version = DBase7Memo
+		}
+	}
+	if hasMemo(fields) && isFoxPro(version) != isFoxPro(r.header.Version) {
+		return nil, fmt.Errorf("Cannot change DBF version from %#x to %#x because of the memo file", r.header.Version, version)
+	}
+	w, err := newWriter(tmp, fields, []Option{Version(version), LanguageDriver(r.header.LanguageDriver)})
+	if err != nil {
+		return nil, err
+	}
+
+	var convErrs ConversionErrors
+	for r.Next() {
+		rec := w.emptyRecord()
+		rec[0] = r.rec[0] // deletion flag
+		offset := 1
+		for i, c := range columns {
+			// the writer may have adjusted the field, e.g. the size of memo fields
+			f := w.fields[i]
+			if c.src >= 0 {
+				buf, err := convertValue(r.fields[c.src], f, r.rec[r.offsets[c.src]:r.offsets[c.src]+int(r.fields[c.src].Size)])
+				if err != nil {
+					convErrs = append(convErrs, &ConversionError{Row: r.row, Field: c.String(), Err: err})
+				} else {
+					copy(rec[offset:offset+int(f.Size)], buf)
+				}
+			}
+			offset += int(f.Size)
+		}
+		if err := w.writeRecord(rec); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return convErrs, w.writeHeader()
+}
+
+// convertValue converts the raw bytes of a value of field from into the raw
+// bytes of a value of field to. Blank values are returned as nil.
+func convertValue(from, to Field, raw []byte) ([]byte, error) {
+	if from.Fieldtype == to.Fieldtype && from.Size == to.Size && from.Precision == to.Precision {
+		return raw, nil
+	}
+	v, err := decodeValue(from, raw)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := v.(string); ok && strings.TrimSpace(s) == "" || v == nil {
+		return nil, nil
+	}
+
+	switch to.Fieldtype {
+	case 'C':
+		if from.Fieldtype != 'C' {
+			v = formatValue(from, raw)
+		}
+	case 'N', 'F', 'I', 'O', 'Y':
+		switch x := v.(type) {
+		case string:
+			x = strings.TrimSpace(x)
+			if i, err := strconv.ParseInt(x, 10, 64); err == nil {
+				v = i
+			} else if f, err := strconv.ParseFloat(x, 64); err == nil {
+				v = f
+			} else {
+				return nil, fmt.Errorf("Cannot convert %q to a number", x)
+			}
+		case float64:
+			if to.Fieldtype == 'I' || (to.Fieldtype != 'O' && to.Fieldtype != 'Y' && to.Precision == 0) {
+				v = int64(x)
+				if float64(int64(x)) != x {
+					return nil, fmt.Errorf("Cannot convert %v to an integer", x)
+				}
+			}
+		}
+	case 'L':
+		if s, ok := v.(string); ok {
+			l, err := decodeValue(to, []byte(strings.TrimSpace(s)))
+			if err != nil {
+				return nil, err
+			}
+			v = l
+		}
+	case 'D', '@', 'T':
+		if s, ok := v.(string); ok {
+			s = strings.TrimSpace(s)
+			layout := "20060102"
+			if len(s) == len("20060102150405") {
+				layout = "20060102150405"
+			}
+			t, err := time.Parse(layout, s)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot convert %q to a date", s)
+			}
+			v = t
+		}
+	}
+
+	buf, err := encodeValue(to, v, OverflowError)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > int(to.Size) {
+		return nil, fmt.Errorf("%q exceeds field length %d", buf, to.Size)
+	}
+	return buf, nil
+}
@@ -0,0 +1,90 @@
+package dbf
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAlter(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+
+	w, err := Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Delete(1)
+	w.Close()
+	if err := os.Chmod(filename, 0640); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Alter(filename, DropField("MISSING")); err == nil {
+		t.Error("expected error when dropping unknown field")
+	}
+	if err := Alter(filename, RenameField("NAME", "count")); err == nil {
+		t.Error("expected error when renaming to existing field")
+	}
+
+	err = Alter(filename,
+		RenameField("name", "TITLE"),
+		ChangeField("COUNT", NumberField("COUNT", 2)),
+		AddField(LogicalField("FLAG")),
+		DropField("DAY"),
+		ChangeField("VALUE", StringField("VALUE", 6)),
+	)
+	convErrs, ok := err.(ConversionErrors)
+	if !ok {
+		t.Fatalf("got error %v, want conversion errors", err)
+	}
+	if len(convErrs) != 1 || convErrs[0].Row != 2 || convErrs[0].Field != "COUNT" {
+		t.Errorf("got conversion errors %v, want one for row 2 of field COUNT", convErrs)
+	}
+	if fi, err := os.Stat(filename); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode() != orig.Mode() {
+		t.Errorf("got mode %v, want %v", fi.Mode(), orig.Mode())
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.Fields() {
+		names = append(names, f.String())
+	}
+	if len(names) != 4 || names[0] != "TITLE" || names[1] != "COUNT" || names[2] != "VALUE" || names[3] != "FLAG" {
+		t.Fatalf("got fields %v, want [TITLE COUNT VALUE FLAG]", names)
+	}
+	if got := r.Header().LanguageDriver; got != 0 {
+		t.Errorf("got language driver %d, want 0", got)
+	}
+	want := [][]string{
+		{"first", "1", "1.500", ""},
+		{"second", "22", "2.250", ""},
+		{"third", "", "-3.125", ""},
+		{"blank", "", "", ""},
+	}
+	for row := range want {
+		if !r.Next() {
+			t.Fatalf("Next failed in row %d: %v", row, r.Err())
+		}
+		if r.Deleted() != (row == 1) {
+			t.Errorf("row %d: got deleted %v", row, r.Deleted())
+		}
+		for field, w := range want[row] {
+			if got := r.Attribute(field); got != w {
+				t.Errorf("row %d, field %d: got %q, want %q", row, field, got, w)
+			}
+		}
+	}
+	if r.Next() {
+		t.Error("got more records than expected")
+	}
+}
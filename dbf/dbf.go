@@ -0,0 +1,229 @@
+// Package dbf reads and writes dBase table files (DBF) as they are used for
+// the attribute tables of shapefiles. It can also be used on its own for
+// plain tables that have no geometry.
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Header holds the metadata that is stored at the beginning of a DBF file.
+type Header struct {
+	// Version is the first byte of the file. It identifies the dBase
+	// variant, e.g. 0x03 for dBase III without memo file.
+	Version byte
+	// LastUpdate is the date of the last update of the table.
+	LastUpdate time.Time
+	// NumRecords is the number of records in the table.
+	NumRecords int
+	// HeaderLength is the number of bytes in the header including the field
+	// descriptors.
+	HeaderLength int
+	// RecordLength is the number of bytes in each record including the
+	// deletion flag.
+	RecordLength int
+	// LanguageDriver identifies the code page of the table.
+	LanguageDriver byte
+}
+
+// rawHeader is the on-disk layout of the first 32 bytes of a DBF file.
+type rawHeader struct {
+	Version          byte
+	Year, Month, Day byte
+	NumRecords       uint32
+	HeaderLength     uint16
+	RecordLength     uint16
+	_                [17]byte
+	LanguageDriver   byte
+	_                [2]byte
+}
+
+// Field representation of a field object in the DBF file
+type Field struct {
+	Name      [11]byte
+	Fieldtype byte
+	Addr      [4]byte // not used
+	Size      uint8
+	Precision uint8
+	Padding   [14]byte
+}
+
+// level7Field is the on-disk layout of a field descriptor in a dBase 7
+// table.
+type level7Field struct {
+	Name      [32]byte
+	Fieldtype byte
+	Size      uint8
+	Precision uint8
+	_         [13]byte
+}
+
+// level7 returns the dBase 7 field descriptor of f.
+func (f Field) level7() level7Field {
+	l7 := level7Field{Fieldtype: f.Fieldtype, Size: f.Size, Precision: f.Precision}
+	copy(l7.Name[:], f.Name[:])
+	return l7
+}
+
+// Returns a string representation of the Field. Currently
+// this only returns field name.
+func (f Field) String() string {
+	return strings.TrimRight(string(f.Name[:]), "\x00")
+}
+
+// StringField returns a Field that can be used to create a DBF file.
+func StringField(name string, length uint8) Field {
+	field := Field{Fieldtype: 'C', Size: length}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// NumberField returns a Field that can be used to create a DBF file.
+func NumberField(name string, length uint8) Field {
+	field := Field{Fieldtype: 'N', Size: length}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// FloatField returns a Field that can be used to create a DBF file. Used to
+// store floating points with precision in the DBF.
+func FloatField(name string, length uint8, precision uint8) Field {
+	field := Field{Fieldtype: 'F', Size: length, Precision: precision}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// DateField returns a Field that can be used to create a DBF file. Used to
+// store Date strings formatted as YYYYMMDD. Data wise this is the same as a
+// StringField with length 8.
+func DateField(name string) Field {
+	field := Field{Fieldtype: 'D', Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// MemoField returns a Field that can be used to create a DBF file. Used to
+// store texts of any length in a separate memo file. In Visual FoxPro tables,
+// the field is written with a size of 4 instead of 10 bytes.
+func MemoField(name string) Field {
+	field := Field{Fieldtype: 'M', Size: 10}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// LogicalField returns a Field that can be used to create a DBF file. Used to
+// store boolean values.
+func LogicalField(name string) Field {
+	field := Field{Fieldtype: 'L', Size: 1}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// IntegerField returns a Field that can be used to create a DBF file. Used to
+// store 32-bit integers in binary form.
+func IntegerField(name string) Field {
+	field := Field{Fieldtype: 'I', Size: 4}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// DoubleField returns a Field that can be used to create a DBF file. Used to
+// store 64-bit floating points in binary form.
+func DoubleField(name string) Field {
+	field := Field{Fieldtype: 'O', Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// TimestampField returns a Field that can be used to create a DBF file. Used
+// to store dates with time of day as in dBase 7.
+func TimestampField(name string) Field {
+	field := Field{Fieldtype: '@', Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// DateTimeField returns a Field that can be used to create a DBF file. Used to
+// store dates with time of day as in FoxPro.
+func DateTimeField(name string) Field {
+	field := Field{Fieldtype: 'T', Size: 8}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// CurrencyField returns a Field that can be used to create a DBF file. Used to
+// store monetary values with four decimals as in FoxPro.
+func CurrencyField(name string) Field {
+	field := Field{Fieldtype: 'Y', Size: 8, Precision: 4}
+	copy(field.Name[:], []byte(name))
+	return field
+}
+
+// errNoTerminator is returned if the field descriptors are not followed by
+// the terminator byte 0x0d.
+var errNoTerminator = errors.New("Field descriptor array terminator not found")
+
+// readHeader reads the header and the field descriptors of a DBF file from r.
+// Afterwards r is positioned at the first record.
+func readHeader(r io.Reader) (Header, []Field, error) {
+	var raw rawHeader
+	if err := binary.Read(r, binary.LittleEndian, &raw); err != nil {
+		return Header{}, nil, fmt.Errorf("Error when reading DBF header: %w", err)
+	}
+	h := Header{
+		Version:        raw.Version,
+		LastUpdate:     time.Date(1900+int(raw.Year), time.Month(raw.Month), int(raw.Day), 0, 0, 0, 0, time.UTC),
+		NumRecords:     int(raw.NumRecords),
+		HeaderLength:   int(raw.HeaderLength),
+		RecordLength:   int(raw.RecordLength),
+		LanguageDriver: raw.LanguageDriver,
+	}
+	if h.HeaderLength < 33 {
+		return h, nil, fmt.Errorf("Invalid DBF header length: %d", h.HeaderLength)
+	}
+	buf := make([]byte, h.HeaderLength-32)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return h, nil, fmt.Errorf("Error when reading DBF field descriptors: %w", err)
+	}
+	// dBase 7 has a language driver name and 48-byte field descriptors
+	start, size := 0, 32
+	if isLevel7(h.Version) {
+		start, size = 36, 48
+	}
+	var fields []Field
+	for i := start; ; i += size {
+		if i >= len(buf) {
+			return h, nil, errNoTerminator
+		}
+		if buf[i] == 0x0d {
+			break
+		}
+		if i+size > len(buf) {
+			return h, nil, errNoTerminator
+		}
+		var f Field
+		if size == 48 {
+			var l7 level7Field
+			binary.Read(bytes.NewReader(buf[i:i+48]), binary.LittleEndian, &l7)
+			f = Field{Fieldtype: l7.Fieldtype, Size: l7.Size, Precision: l7.Precision}
+			copy(f.Name[:], l7.Name[:])
+		} else {
+			binary.Read(bytes.NewReader(buf[i:i+32]), binary.LittleEndian, &f)
+		}
+		fields = append(fields, f)
+	}
+	length := 1
+	for _, f := range fields {
+		length += int(f.Size)
+	}
+	if length > h.RecordLength {
+		return h, nil, fmt.Errorf("DBF record length %d is too short for the fields (%d)", h.RecordLength, length)
+	}
+	return h, fields, nil
+}
@@ -0,0 +1,282 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dbtBlockSize is the block size of the dBase III memo files that are
+// written by this package.
+const dbtBlockSize = 512
+
+// isFoxPro reports whether the DBF version byte v belongs to a FoxPro table,
+// whose memo file is an .fpt file.
+func isFoxPro(v byte) bool {
+	switch v {
+	case 0x30, 0x31, 0x32, 0xf5, 0xfb:
+		return true
+	}
+	return false
+}
+
+// MemoExtensions returns the extensions of the memo files that can belong to
+// a table with the given version byte, in the order in which they are looked
+// up: .fpt comes first for FoxPro tables and .dbt for all others.
+func MemoExtensions(version byte) []string {
+	if isFoxPro(version) {
+		return []string{".fpt", ".dbt"}
+	}
+	return []string{".dbt", ".fpt"}
+}
+
+// binaryMemo reports whether the memo field f holds the block number as a
+// 4-byte binary number instead of as text.
+func binaryMemo(f Field) bool {
+	return f.Fieldtype == 'M' && f.Size == 4
+}
+
+// hasMemo reports whether any of fields stores its values in a memo file.
+func hasMemo(fields []Field) bool {
+	for _, f := range fields {
+		if f.Fieldtype == 'M' {
+			return true
+		}
+	}
+	return false
+}
+
+// memoFilename returns the name of an existing memo file that belongs to the
+// DBF file at filename, trying the extensions in the given order in lower and
+// upper case. It returns the empty string if there is none.
+func memoFilename(filename string, exts ...string) string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for _, ext := range exts {
+		for _, e := range []string{ext, strings.ToUpper(ext)} {
+			if _, err := os.Stat(base + e); err == nil {
+				return base + e
+			}
+		}
+	}
+	return ""
+}
+
+// memoReader reads the contents of a dBase (.dbt) or FoxPro (.fpt) memo file.
+type memoReader struct {
+	r         io.ReadSeeker
+	foxPro    bool
+	blockSize int64
+}
+
+// newMemoReader reads the header of the memo file from r.
+func newMemoReader(r io.ReadSeeker, foxPro bool) (*memoReader, error) {
+	header := make([]byte, 22)
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("Error when reading memo header: %w", err)
+	}
+	m := &memoReader{r: r, foxPro: foxPro}
+	if foxPro {
+		m.blockSize = int64(binary.BigEndian.Uint16(header[6:8]))
+	} else {
+		// only dBase IV stores the block size, dBase III always uses 512
+		m.blockSize = int64(binary.LittleEndian.Uint16(header[20:22]))
+		if m.blockSize < 64 {
+			m.blockSize = dbtBlockSize
+		}
+	}
+	if m.blockSize == 0 {
+		return nil, errors.New("Invalid memo block size 0")
+	}
+	return m, nil
+}
+
+// read returns the memo that starts at the given block.
+func (m *memoReader) read(block int64) (string, error) {
+	if _, err := m.r.Seek(block*m.blockSize, io.SeekStart); err != nil {
+		return "", err
+	}
+	var head [8]byte
+	if _, err := io.ReadFull(m.r, head[:]); err != nil {
+		return "", fmt.Errorf("Error when reading memo block %d: %w", block, err)
+	}
+	var n int64
+	switch {
+	case m.foxPro:
+		n = int64(binary.BigEndian.Uint32(head[4:]))
+	case bytes.Equal(head[:4], []byte{0xff, 0xff, 0x08, 0x00}):
+		// dBase IV, the length includes the block header
+		n = int64(binary.LittleEndian.Uint32(head[4:])) - 8
+	default:
+		// dBase III, the memo is terminated by 0x1a
+		if i := bytes.IndexByte(head[:], 0x1a); i >= 0 {
+			return string(head[:i]), nil
+		}
+		buf := bytes.NewBuffer(head[:])
+		chunk := make([]byte, m.blockSize)
+		for {
+			k, err := m.r.Read(chunk)
+			if i := bytes.IndexByte(chunk[:k], 0x1a); i >= 0 {
+				buf.Write(chunk[:i])
+				return buf.String(), nil
+			}
+			buf.Write(chunk[:k])
+			if err == io.EOF {
+				return buf.String(), nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("Error when reading memo block %d: %w", block, err)
+			}
+		}
+	}
+	if n < 0 {
+		return "", fmt.Errorf("Invalid length of memo block %d", block)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(m.r, buf); err != nil {
+		return "", fmt.Errorf("Error when reading memo block %d: %w", block, err)
+	}
+	return string(buf), nil
+}
+
+// memoBlock decodes the block number that is stored in a memo field. It is
+// either a number in ASCII or, in Visual FoxPro, a 32-bit integer. Blank
+// values are returned as 0.
+func memoBlock(f Field, raw []byte) (int64, error) {
+	if binaryMemo(f) {
+		return int64(binary.LittleEndian.Uint32(raw)), nil
+	}
+	s := strings.Trim(string(raw), " \x00")
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memo block %q in field %s", s, f)
+	}
+	return n, nil
+}
+
+// fptBlockSize is the block size of the FoxPro memo files that are written
+// by this package.
+const fptBlockSize = 64
+
+// memoWriter appends memos to a memo file. The format depends on the version
+// of the table: dBase III and dBase IV memo files (.dbt) use blocks of 512
+// bytes, FoxPro memo files (.fpt) use blocks of 64 bytes and big-endian
+// numbers.
+type memoWriter struct {
+	w         io.WriteSeeker
+	version   byte
+	blockSize int64
+	next      uint32 // the next free block
+}
+
+// newMemoWriter starts a new memo file in w for a table with the given
+// version.
+func newMemoWriter(w io.WriteSeeker, version byte) (*memoWriter, error) {
+	m := &memoWriter{w: w, version: version, blockSize: dbtBlockSize, next: 1}
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 512)
+	switch {
+	case isFoxPro(version):
+		m.blockSize = fptBlockSize
+		m.next = 512 / fptBlockSize
+		binary.BigEndian.PutUint16(header[6:], fptBlockSize)
+	case isLevel7(version):
+		binary.LittleEndian.PutUint16(header[20:], dbtBlockSize)
+	default:
+		header[16] = 0x03 // dBase III
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// appendMemoWriter continues the memo file in rw of a table with the given
+// version.
+func appendMemoWriter(rw io.ReadWriteSeeker, version byte) (*memoWriter, error) {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var header [22]byte
+	if _, err := io.ReadFull(rw, header[:]); err != nil {
+		return nil, fmt.Errorf("Error when reading memo header: %w", err)
+	}
+	m := &memoWriter{w: rw, version: version, blockSize: dbtBlockSize}
+	if isFoxPro(version) {
+		m.blockSize = int64(binary.BigEndian.Uint16(header[6:]))
+		m.next = binary.BigEndian.Uint32(header[:4])
+		if m.blockSize == 0 {
+			return nil, errors.New("Invalid memo block size 0")
+		}
+		return m, nil
+	}
+	if bs := binary.LittleEndian.Uint16(header[20:]); bs >= 64 && bs != dbtBlockSize {
+		return nil, fmt.Errorf("Unsupported memo block size %d", bs)
+	}
+	m.next = binary.LittleEndian.Uint32(header[:4])
+	return m, nil
+}
+
+// write stores s in the next free blocks and returns the number of the first
+// of them.
+func (m *memoWriter) write(s string) (uint32, error) {
+	block := m.next
+	if _, err := m.w.Seek(int64(block)*m.blockSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var data []byte
+	switch {
+	case isFoxPro(m.version):
+		// text type and length
+		data = make([]byte, 8, 8+len(s))
+		binary.BigEndian.PutUint32(data[:4], 1)
+		binary.BigEndian.PutUint32(data[4:], uint32(len(s)))
+		data = append(data, s...)
+	case isLevel7(m.version):
+		// dBase IV block header with the length including the header
+		data = make([]byte, 8, 8+len(s))
+		copy(data, []byte{0xff, 0xff, 0x08, 0x00})
+		binary.LittleEndian.PutUint32(data[4:], uint32(len(s)+8))
+		data = append(data, s...)
+	default:
+		data = append([]byte(s), 0x1a, 0x1a)
+	}
+	blocks := (int64(len(data)) + m.blockSize - 1) / m.blockSize
+	buf := make([]byte, blocks*m.blockSize)
+	copy(buf, data)
+	if _, err := m.w.Write(buf); err != nil {
+		return 0, err
+	}
+	m.next += uint32(blocks)
+	return block, nil
+}
+
+// close writes the number of the next free block into the header and closes
+// the underlying writer if it implements io.Closer.
+func (m *memoWriter) close() error {
+	_, err := m.w.Seek(0, io.SeekStart)
+	if err == nil && isFoxPro(m.version) {
+		err = binary.Write(m.w, binary.BigEndian, m.next)
+	} else if err == nil {
+		err = binary.Write(m.w, binary.LittleEndian, m.next)
+	}
+	if c, ok := m.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMemoRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-dbf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "memo.dbf")
+	w, err := Create(filename, []Field{MemoField("NOTES")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := []string{"first", strings.Repeat("x", 1500), ""}
+	for _, note := range notes {
+		row, _ := w.AddRecord()
+		if err := w.WriteAttribute(row, 0, note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// append another memo behind the existing ones
+	w, err = Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, _ := w.AddRecord()
+	if err := w.WriteAttribute(row, 0, "appended"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	notes = append(notes, "appended")
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Header().Version != 0x83 {
+		t.Errorf("got version %#x, want 0x83", r.Header().Version)
+	}
+	for r.Next() {
+		if got, want := r.Attribute(0), notes[r.Row()]; got != want {
+			t.Errorf("row %d: got %q, want %q", r.Row(), got, want)
+		}
+		if v, err := r.Value(0); err != nil || v != notes[r.Row()] {
+			t.Errorf("row %d: got value %q (%v), want %q", r.Row(), v, err, notes[r.Row()])
+		}
+	}
+}
+
+func TestReadMemoFormats(t *testing.T) {
+	// dBase IV: block size in the header, blocks start with ff ff 08 00
+	dbt := make([]byte, 64*3)
+	binary.LittleEndian.PutUint16(dbt[20:], 64)
+	copy(dbt[64:], []byte{0xff, 0xff, 0x08, 0x00})
+	binary.LittleEndian.PutUint32(dbt[68:], 8+5)
+	copy(dbt[72:], "dBase")
+
+	// FoxPro: big-endian header and block headers
+	fpt := make([]byte, 32*3)
+	binary.BigEndian.PutUint16(fpt[6:], 32)
+	binary.BigEndian.PutUint32(fpt[32:], 1)
+	binary.BigEndian.PutUint32(fpt[36:], 3)
+	copy(fpt[40:], "fox")
+
+	tests := []struct {
+		name   string
+		memo   []byte
+		foxPro bool
+		want   string
+	}{
+		{"dBase IV", dbt, false, "dBase"},
+		{"FoxPro", fpt, true, "fox"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := newMemoReader(bytes.NewReader(test.memo), test.foxPro)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.read(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVisualFoxProMemo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-dbf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "vfp.dbf")
+	w, err := Create(filename, []Field{MemoField("NOTES"), NumberField("N", 3)}, Version(VisualFoxPro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := []string{"first", "", "third"}
+	for _, note := range notes {
+		row, _ := w.AddRecord()
+		if err := w.WriteAttribute(row, 0, note); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteAttribute(row, 1, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vfp.fpt")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if f := r.Fields()[0]; f.Size != 4 {
+		t.Errorf("got memo field of size %d, want 4", f.Size)
+	}
+	for r.Next() {
+		if got, want := r.Attribute(0), notes[r.Row()]; got != want {
+			t.Errorf("row %d: got %q, want %q", r.Row(), got, want)
+		}
+		if got, want := r.Attribute(1), strconv.Itoa(r.Row()); got != want {
+			t.Errorf("row %d: got number %q, want %q", r.Row(), got, want)
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
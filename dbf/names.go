@@ -0,0 +1,110 @@
+package dbf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxNameLength is the maximum length of a field name. The name is stored in
+// 11 bytes, which are terminated by a zero byte.
+const maxNameLength = 10
+
+// checkName returns an error if f has no valid field name, i.e. if it is
+// empty, longer than 10 characters or contains characters other than
+// printable ASCII characters.
+func checkName(f Field) error {
+	name := f.String()
+	switch {
+	case name == "":
+		return fmt.Errorf("Empty field name")
+	case f.Name[maxNameLength] != 0:
+		return fmt.Errorf("Field name %s is longer than %d characters", name, maxNameLength)
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] <= ' ' || name[i] > '~' {
+			return fmt.Errorf("Field name %q contains invalid character %q", name, name[i])
+		}
+	}
+	return nil
+}
+
+// CheckFields returns an error if one of fields has an invalid name or if
+// two fields have the same name. Names are compared case-insensitively. A
+// valid name has at most 10 characters, which must be printable ASCII
+// characters other than space.
+func CheckFields(fields []Field) error {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if err := checkName(f); err != nil {
+			return err
+		}
+		name := strings.ToUpper(f.String())
+		if seen[name] {
+			return fmt.Errorf("Duplicate field name %s", f)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// UniqueNames turns names into valid and unique field names. Characters
+// other than ASCII letters, digits and underscores are replaced by
+// underscores and the names are cut to 10 characters. Names that are used
+// more than once, compared case-insensitively, get a numeric suffix, e.g.
+// POPULATION_2020 and POPULATION_2021 become POPULATION and POPULATI_1.
+func UniqueNames(names []string) []string {
+	unique := make([]string, len(names))
+	seen := make(map[string]bool)
+	for i, name := range names {
+		base := sanitizeName(name)
+		name = base
+		for n := 1; seen[strings.ToUpper(name)]; n++ {
+			suffix := "_" + strconv.Itoa(n)
+			name = base
+			if len(name)+len(suffix) > maxNameLength {
+				name = name[:maxNameLength-len(suffix)]
+			}
+			name += suffix
+		}
+		seen[strings.ToUpper(name)] = true
+		unique[i] = name
+	}
+	return unique
+}
+
+// sanitizeName replaces all invalid characters in name by underscores and
+// cuts it to the maximum length.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if b.Len() == maxNameLength {
+			break
+		}
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "FIELD"
+	}
+	return b.String()
+}
+
+// FieldIndex returns the index of the field called name in fields. An exact
+// match is preferred, otherwise the names are compared case-insensitively.
+func FieldIndex(fields []Field, name string) (int, bool) {
+	for i, f := range fields {
+		if f.String() == name {
+			return i, true
+		}
+	}
+	for i, f := range fields {
+		if strings.EqualFold(f.String(), name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,55 @@
+package dbf
+
+import (
+	"testing"
+)
+
+func TestUniqueNames(t *testing.T) {
+	names := UniqueNames([]string{"POPULATION_2020", "POPULATION_2021", "name", "NAME", "größe", "", "a b"})
+	want := []string{"POPULATION", "POPULATI_1", "name", "NAME_1", "gr__e", "FIELD", "a_b"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("name %d: got %q, want %q", i, names[i], want[i])
+		}
+	}
+	if err := CheckFields([]Field{StringField(names[0], 1), StringField(names[1], 1)}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	invalid := [][]Field{
+		{StringField("POPULATION_2020", 5)},
+		{StringField("NAME", 5), NumberField("name", 5)},
+		{StringField("größe", 5)},
+		{StringField("", 5)},
+		{StringField("A B", 5)},
+	}
+	for _, fields := range invalid {
+		if err := CheckFields(fields); err == nil {
+			t.Errorf("expected error for fields %v", fields)
+		}
+	}
+	if _, err := Create("invalid.dbf", invalid[0]); err == nil {
+		t.Error("expected error when creating table with invalid fields")
+	}
+}
+
+func TestFieldIndex(t *testing.T) {
+	fields := []Field{StringField("Name", 5), StringField("NAME", 5), StringField("VALUE", 5)}
+	tests := []struct {
+		name  string
+		index int
+		ok    bool
+	}{
+		{"NAME", 1, true},
+		{"Name", 0, true},
+		{"value", 2, true},
+		{"missing", -1, false},
+	}
+	for _, test := range tests {
+		if i, ok := FieldIndex(fields, test.name); i != test.index || ok != test.ok {
+			t.Errorf("%s: got %d, %v, want %d, %v", test.name, i, ok, test.index, test.ok)
+		}
+	}
+}
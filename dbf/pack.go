@@ -0,0 +1,83 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jonas-p/go-shp/internal/tempfile"
+)
+
+// Pack removes the records that are marked as deleted from the DBF file at
+// filename. The table is written to a temporary file first, which then
+// replaces the original file. Memo blocks of the removed records are not
+// reclaimed.
+func Pack(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, _, err := readHeader(f); err != nil {
+		return err
+	}
+
+	tmp, err := tempfile.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := PackTo(tmp, f); err != nil {
+		tmp.Discard()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		tmp.Discard()
+		return err
+	}
+	f.Close()
+	return tmp.Replace()
+}
+
+// PackTo writes the table read from r to w without the records that are
+// marked as deleted, which leaves r unchanged.
+func PackTo(w io.WriteSeeker, r io.ReadSeeker) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h, _, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	return pack(w, r, h)
+}
+
+// pack copies the header and all records that are not deleted of the table
+// with header h from r to w.
+func pack(w io.WriteSeeker, r io.ReadSeeker, h Header) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(w, r, int64(h.HeaderLength)); err != nil {
+		return fmt.Errorf("Error when copying DBF header: %w", err)
+	}
+	rec := make([]byte, h.RecordLength)
+	num := 0
+	for row := 0; row < h.NumRecords; row++ {
+		if _, err := io.ReadFull(r, rec); err != nil {
+			return fmt.Errorf("Error when reading DBF row %d: %w", row, err)
+		}
+		if rec[0] == '*' {
+			continue
+		}
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+		num++
+	}
+	// number of records
+	if _, err := w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, uint32(num))
+}
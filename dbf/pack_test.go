@@ -0,0 +1,68 @@
+package dbf
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+
+	w, err := Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []int{1, 3} {
+		if err := w.Delete(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Delete(4); err == nil {
+		t.Error("expected error when deleting row out of range")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row, want := range []bool{false, true, false, true} {
+		if got, err := r.IsDeleted(row); err != nil || got != want {
+			t.Errorf("row %d: got deleted %v (%v), want %v", row, got, err, want)
+		}
+	}
+	var deleted []bool
+	for r.Next() {
+		deleted = append(deleted, r.Deleted())
+	}
+	if len(deleted) != 4 || !deleted[1] || !deleted[3] {
+		t.Errorf("got deletion flags %v", deleted)
+	}
+	r.Close()
+
+	if err := Pack(filename); err != nil {
+		t.Fatal(err)
+	}
+	r, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.NumRecords(); got != 2 {
+		t.Fatalf("got %d records after packing, want 2", got)
+	}
+	for _, want := range []string{"first", "third"} {
+		if !r.Next() {
+			t.Fatalf("Next failed: %v", r.Err())
+		}
+		if got := r.Attribute(0); got != want {
+			t.Errorf("row %d: got %q, want %q", r.Row(), got, want)
+		}
+		if r.Deleted() {
+			t.Errorf("row %d is still deleted", r.Row())
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package dbf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reader reads the records of a DBF table. The records can be iterated over
+// with Next. If the underlying reader implements io.Seeker, records can also
+// be accessed in arbitrary order with ReadRecord and ReadAttribute.
+type Reader struct {
+	r      io.Reader
+	header Header
+	fields []Field
+	// offsets holds the position of each field within a record. The deletion
+	// flag is at position 0.
+	offsets []int
+
+	row int // index of the current record, -1 before the first one
+	rec []byte
+	err error
+
+	lenient bool
+	recErr  error // problem with the current record in lenient mode
+
+	memo *memoReader // nil if there is no memo file
+}
+
+// Open opens the DBF file at filename for reading. If the table has memo
+// fields, the memo file with the same name and the extension .dbt (dBase) or
+// .fpt (FoxPro) is opened as well.
+func Open(filename string) (*Reader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !hasMemo(r.fields) {
+		return r, nil
+	}
+	name := memoFilename(filename, MemoExtensions(r.header.Version)...)
+	if name == "" {
+		return r, nil // without memo file, the block numbers are returned
+	}
+	m, err := os.Open(name)
+	if err == nil {
+		err = r.SetMemo(m, strings.ToLower(filepath.Ext(name)) == ".fpt")
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewReader reads the header of a DBF table from r and returns a Reader that
+// is positioned before the first record. If r implements io.Closer, it is
+// closed by Close.
+func NewReader(r io.Reader) (*Reader, error) {
+	h, fields, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	dr := &Reader{
+		r:       r,
+		header:  h,
+		fields:  fields,
+		offsets: make([]int, len(fields)),
+		row:     -1,
+		rec:     make([]byte, h.RecordLength),
+	}
+	offset := 1
+	for i, f := range fields {
+		dr.offsets[i] = offset
+		offset += int(f.Size)
+	}
+	return dr, nil
+}
+
+// SetMemo sets the memo file from which the values of memo fields are read.
+// If foxPro is true, m is read as FoxPro (.fpt) memo file, otherwise as
+// dBase III or IV (.dbt) memo file. If m implements io.Closer, it is closed
+// by Close. Without memo file, the values of memo fields are the numbers of
+// the blocks in which they are stored.
+func (r *Reader) SetMemo(m io.ReadSeeker, foxPro bool) error {
+	memo, err := newMemoReader(m, foxPro)
+	if err != nil {
+		return err
+	}
+	r.memo = memo
+	return nil
+}
+
+// SetLenient sets whether Next continues after a record with an invalid
+// deletion indicator. In lenient mode, such a record is returned like a
+// record that is not deleted, and the problem is reported by RecordErr.
+func (r *Reader) SetLenient(lenient bool) {
+	r.lenient = lenient
+}
+
+// Header returns the metadata from the header of the table.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Fields returns the fields of the table.
+func (r *Reader) Fields() []Field {
+	return r.fields
+}
+
+// FieldIndex returns the index of the field called name. An exact match is
+// preferred, otherwise the names are compared case-insensitively.
+func (r *Reader) FieldIndex(name string) (int, bool) {
+	return FieldIndex(r.fields, name)
+}
+
+// NumRecords returns the number of records in the table.
+func (r *Reader) NumRecords() int {
+	return r.header.NumRecords
+}
+
+// Next reads the next record, which is then available through the Attribute
+// and Value methods. It returns false when all records have been read or
+// when an error occurred.
+func (r *Reader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.row+1 >= r.header.NumRecords {
+		r.err = io.EOF
+		return false
+	}
+	if s, ok := r.r.(io.Seeker); ok {
+		if _, err := s.Seek(r.recordOffset(r.row+1), io.SeekStart); err != nil {
+			r.err = fmt.Errorf("Error when seeking to DBF row: %w", err)
+			return false
+		}
+	}
+	if _, err := io.ReadFull(r.r, r.rec); err != nil {
+		r.err = fmt.Errorf("Error when reading DBF row: %w", err)
+		return false
+	}
+	r.row++
+	r.recErr = nil
+	if r.rec[0] != ' ' && r.rec[0] != '*' {
+		err := fmt.Errorf("Attribute row %d starts with %w", r.row, ErrDeletionIndicator)
+		if !r.lenient {
+			r.err = err
+			return false
+		}
+		r.recErr = err
+	}
+	return true
+}
+
+// RecordErr returns the problem with the current record that was ignored in
+// lenient mode, or nil if the record is valid.
+func (r *Reader) RecordErr() error {
+	return r.recErr
+}
+
+// Deleted reports whether the current record is marked as deleted.
+func (r *Reader) Deleted() bool {
+	return r.row >= 0 && r.rec[0] == '*'
+}
+
+// IsDeleted reports whether the record at row is marked as deleted. It
+// requires random access to the table.
+func (r *Reader) IsDeleted(row int) (bool, error) {
+	s, ok := r.r.(io.ReadSeeker)
+	if !ok {
+		return false, errNoSeeker
+	}
+	if row < 0 || row >= r.header.NumRecords {
+		return false, fmt.Errorf("DBF row %d out of range", row)
+	}
+	if _, err := s.Seek(r.recordOffset(row), io.SeekStart); err != nil {
+		return false, err
+	}
+	flag := make([]byte, 1)
+	if _, err := io.ReadFull(s, flag); err != nil {
+		return false, fmt.Errorf("Error when reading DBF row: %w", err)
+	}
+	if flag[0] != ' ' && flag[0] != '*' {
+		return false, fmt.Errorf("Attribute row %d starts with %w", row, ErrDeletionIndicator)
+	}
+	return flag[0] == '*', nil
+}
+
+// Row returns the index of the current record, starting at 0.
+func (r *Reader) Row() int {
+	return r.row
+}
+
+// Err returns the first non-EOF error that was encountered.
+func (r *Reader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+// ErrDeletionIndicator is returned for a record whose first byte is neither
+// ' ' nor '*'.
+var ErrDeletionIndicator = errors.New("incorrect deletion indicator")
+
+// errNoSeeker is returned by methods that need random access if the
+// underlying reader does not implement io.Seeker.
+var errNoSeeker = errors.New("DBF reader does not support random access")
+
+func (r *Reader) recordOffset(row int) int64 {
+	return int64(r.header.HeaderLength) + int64(row)*int64(r.header.RecordLength)
+}
+
+// ReadRecord makes the record at row the current record.
+func (r *Reader) ReadRecord(row int) error {
+	s, ok := r.r.(io.ReadSeeker)
+	if !ok {
+		return errNoSeeker
+	}
+	if row < 0 || row >= r.header.NumRecords {
+		return fmt.Errorf("DBF row %d out of range", row)
+	}
+	if _, err := s.Seek(r.recordOffset(row), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(s, r.rec); err != nil {
+		return fmt.Errorf("Error when reading DBF row: %w", err)
+	}
+	r.row = row
+	return nil
+}
+
+// Attribute returns the value of the n-th field of the current record as a
+// string with leading and trailing spaces removed. Values of fields that are
+// stored in binary form, such as 'I' or 'T', are formatted as text.
+func (r *Reader) Attribute(n int) string {
+	if r.row < 0 || n < 0 || n >= len(r.fields) {
+		return ""
+	}
+	s, _ := r.format(r.fields[n], r.rec[r.offsets[n]:r.offsets[n]+int(r.fields[n].Size)])
+	return s
+}
+
+// format is like formatValue, but reads the contents of memo fields from the
+// memo file.
+func (r *Reader) format(f Field, raw []byte) (string, error) {
+	if f.Fieldtype != 'M' || r.memo == nil {
+		return formatValue(f, raw), nil
+	}
+	block, err := memoBlock(f, raw)
+	if err != nil || block == 0 {
+		return "", err
+	}
+	return r.memo.read(block)
+}
+
+// Value returns the value of the n-th field of the current record converted
+// to a Go value according to the type of the field. Blank values are
+// returned as nil.
+func (r *Reader) Value(n int) (interface{}, error) {
+	if r.row < 0 {
+		return nil, errors.New("No current DBF row")
+	}
+	if n < 0 || n >= len(r.fields) {
+		return nil, fmt.Errorf("DBF field %d out of range", n)
+	}
+	f, raw := r.fields[n], r.rec[r.offsets[n]:r.offsets[n]+int(r.fields[n].Size)]
+	if f.Fieldtype == 'M' && r.memo != nil {
+		return r.format(f, raw)
+	}
+	return decodeValue(f, raw)
+}
+
+// ReadAttribute returns the attribute value at row for field in the table as
+// a string like Attribute does. Both values start at 0. It requires random
+// access to the table.
+func (r *Reader) ReadAttribute(row int, field int) string {
+	s, ok := r.r.(io.ReadSeeker)
+	if !ok || field < 0 || field >= len(r.fields) {
+		return ""
+	}
+	s.Seek(r.recordOffset(row)+int64(r.offsets[field]), io.SeekStart)
+	buf := make([]byte, r.fields[field].Size)
+	io.ReadFull(s, buf)
+	v, _ := r.format(r.fields[field], buf)
+	return v
+}
+
+// ReadValue is like Value, but for the record at row. It requires random
+// access to the table.
+func (r *Reader) ReadValue(row int, field int) (interface{}, error) {
+	if err := r.ReadRecord(row); err != nil {
+		return nil, err
+	}
+	return r.Value(field)
+}
+
+// Close closes the underlying reader and the memo file if they implement
+// io.Closer.
+func (r *Reader) Close() error {
+	var err error
+	if c, ok := r.r.(io.Closer); ok {
+		err = c.Close()
+	}
+	if r.memo != nil {
+		if c, ok := r.memo.r.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,222 @@
+package dbf
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadHeader(t *testing.T) {
+	r, err := Open("../test_files/polygon.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	h := r.Header()
+	if h.Version != 3 {
+		t.Errorf("got version %d, want 3", h.Version)
+	}
+	if want := time.Date(2014, 5, 13, 0, 0, 0, 0, time.UTC); !h.LastUpdate.Equal(want) {
+		t.Errorf("got last update %v, want %v", h.LastUpdate, want)
+	}
+	if h.LanguageDriver != 0x57 {
+		t.Errorf("got language driver %#x, want 0x57", h.LanguageDriver)
+	}
+	if r.NumRecords() != 1 {
+		t.Errorf("got %d records, want 1", r.NumRecords())
+	}
+	var names []string
+	for _, f := range r.Fields() {
+		names = append(names, f.String())
+	}
+	if want := []string{"polygon_ID", "AREA"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got fields %v, want %v", names, want)
+	}
+}
+
+func createTestTable(t *testing.T) (dir, filename string) {
+	dir, err := ioutil.TempDir("", "go-shp-dbf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename = filepath.Join(dir, "table.dbf")
+	w, err := Create(filename, []Field{
+		StringField("NAME", 10),
+		NumberField("COUNT", 4),
+		FloatField("VALUE", 8, 3),
+		DateField("DAY"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]interface{}{
+		{"first", 1, 1.5, "20180102"},
+		{"second", 22, 2.25, "20180203"},
+		{"third", 333, -3.125, "20180304"},
+	}
+	for _, values := range rows {
+		row, err := w.AddRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for field, v := range values {
+			if err := w.WriteAttribute(row, field, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	row, _ := w.AddRecord() // blank record
+	w.WriteAttribute(row, 0, "blank")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir, filename
+}
+
+func TestReadWrittenTable(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	want := [][]interface{}{
+		{"first", int64(1), 1.5, time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"second", int64(22), 2.25, time.Date(2018, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"third", int64(333), -3.125, time.Date(2018, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"blank", nil, nil, nil},
+	}
+	n := 0
+	for r.Next() {
+		for field, v := range want[r.Row()] {
+			got, err := r.Value(field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("row %d, field %d: got %#v, want %#v", r.Row(), field, got, v)
+			}
+		}
+		n++
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	if n != len(want) {
+		t.Fatalf("read %d records, want %d", n, len(want))
+	}
+
+	// random access
+	if got := r.ReadAttribute(1, 0); got != "second" {
+		t.Errorf("got %q, want %q", got, "second")
+	}
+	if got, err := r.ReadValue(2, 2); err != nil || got != -3.125 {
+		t.Errorf("got %v (%v), want -3.125", got, err)
+	}
+	if err := r.ReadRecord(len(want)); err == nil {
+		t.Error("read record out of range without error")
+	}
+}
+
+func TestReadSequentially(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// strings.Reader implements io.Seeker, so hide it
+	r, err := NewReader(struct{ io.Reader }{strings.NewReader(string(b))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for r.Next() {
+		names = append(names, r.Attribute(0))
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	if want := []string{"first", "second", "third", "blank"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	if err := r.ReadRecord(0); err == nil {
+		t.Error("got random access without io.Seeker")
+	}
+}
+
+func TestReadLenient(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[int(r.Header().HeaderLength)+r.Header().RecordLength] = '?'
+	r, _ = NewReader(strings.NewReader(string(b)))
+	if _, err := r.IsDeleted(1); !errors.Is(err, ErrDeletionIndicator) {
+		t.Errorf("got error %v for invalid deletion indicator, want ErrDeletionIndicator", err)
+	}
+	for r.Next() {
+	}
+	if !errors.Is(r.Err(), ErrDeletionIndicator) || r.Row() != 1 {
+		t.Errorf("strict: stopped at row %d with error %v", r.Row(), r.Err())
+	}
+
+	r, _ = NewReader(strings.NewReader(string(b)))
+	r.SetLenient(true)
+	var bad []int
+	n := 0
+	for r.Next() {
+		if r.RecordErr() != nil {
+			bad = append(bad, r.Row())
+		}
+		n++
+	}
+	if r.Err() != nil || n != 4 || !reflect.DeepEqual(bad, []int{1}) {
+		t.Errorf("lenient: read %d records with error %v and bad rows %v, want 4 and [1]", n, r.Err(), bad)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+	w, err := Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, err := w.AddRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row != 4 {
+		t.Fatalf("got row %d, want 4", row)
+	}
+	w.WriteAttribute(row, 0, "appended")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.NumRecords() != 5 {
+		t.Fatalf("got %d records, want 5", r.NumRecords())
+	}
+	if got := r.ReadAttribute(4, 0); got != "appended" {
+		t.Errorf("got %q, want %q", got, "appended")
+	}
+}
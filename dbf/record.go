@@ -0,0 +1,97 @@
+package dbf
+
+import (
+	"fmt"
+)
+
+// Record holds the values of one record of a table together with its
+// fields. The values are typed as returned by Reader.Value.
+type Record struct {
+	fields []Field
+	values []interface{}
+}
+
+// NewRecord returns a Record with the given fields and values, which can be
+// passed to Writer.WriteRecord.
+func NewRecord(fields []Field, values []interface{}) (Record, error) {
+	if len(fields) != len(values) {
+		return Record{}, fmt.Errorf("Got %d values for %d fields", len(values), len(fields))
+	}
+	return Record{fields: fields, values: values}, nil
+}
+
+// Fields returns the fields of the record.
+func (r Record) Fields() []Field {
+	return r.fields
+}
+
+// Len returns the number of values in the record.
+func (r Record) Len() int {
+	return len(r.values)
+}
+
+// Value returns the n-th value of the record. It returns nil if n is out of
+// range.
+func (r Record) Value(n int) interface{} {
+	if n < 0 || n >= len(r.values) {
+		return nil
+	}
+	return r.values[n]
+}
+
+// Get returns the value of the field called name, which is looked up like
+// with FieldIndex. It returns false if there is no such field.
+func (r Record) Get(name string) (interface{}, bool) {
+	i, ok := FieldIndex(r.fields, name)
+	if !ok {
+		return nil, false
+	}
+	return r.values[i], true
+}
+
+// Values returns all values of the record in the order of the fields.
+func (r Record) Values() []interface{} {
+	return r.values
+}
+
+// Map returns the values of the record by the names of their fields.
+func (r Record) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(r.values))
+	for i, f := range r.fields {
+		m[f.String()] = r.values[i]
+	}
+	return m
+}
+
+// Record returns the values of the current record.
+func (r *Reader) Record() (Record, error) {
+	values := make([]interface{}, len(r.fields))
+	for i := range r.fields {
+		v, err := r.Value(i)
+		if err != nil {
+			return Record{}, fmt.Errorf("Error when reading DBF row %d: %w", r.row, err)
+		}
+		values[i] = v
+	}
+	return Record{fields: r.fields, values: values}, nil
+}
+
+// WriteRecord writes the values of rec into the given row. The values are
+// matched to the fields of the table by name like with FieldIndex, so rec
+// can come from a table with a different schema. Fields of the table that
+// are missing in rec are not changed.
+func (w *Writer) WriteRecord(row int, rec Record) error {
+	if row < 0 || row >= w.num {
+		return fmt.Errorf("DBF row %d out of range", row)
+	}
+	for i, f := range w.fields {
+		v, ok := rec.Get(f.String())
+		if !ok {
+			continue
+		}
+		if err := w.WriteAttribute(row, i, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package dbf
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	dir, filename := createTestTable(t)
+	defer os.RemoveAll(dir)
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.ReadRecord(1); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := r.Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Len() != 4 || rec.Value(0) != "second" || rec.Value(4) != nil {
+		t.Errorf("got values %v", rec.Values())
+	}
+	if v, ok := rec.Get("count"); !ok || v != int64(22) {
+		t.Errorf("got COUNT %v (%v), want 22", v, ok)
+	}
+	if _, ok := rec.Get("MISSING"); ok {
+		t.Error("got value for missing field")
+	}
+	m := rec.Map()
+	if m["VALUE"] != 2.25 || m["DAY"] != time.Date(2018, 2, 3, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("got map %v", m)
+	}
+
+	// copy the record into a table with a different schema
+	w, err := Create(filename+".copy", []Field{NumberField("COUNT", 5), StringField("OTHER", 3), StringField("NAME", 8)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename + ".copy")
+	row, _ := w.AddRecord()
+	if err := w.WriteRecord(row, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRecord(1, rec); err == nil {
+		t.Error("expected error when writing row out of range")
+	}
+	w.Close()
+
+	c, err := Open(filename + ".copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Next()
+	for i, want := range []string{"22", "", "second"} {
+		if got := c.Attribute(i); got != want {
+			t.Errorf("field %d: got %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// julianUnixEpoch is the Julian day number of 1970-01-01.
+const julianUnixEpoch = 2440588
+
+// binarySizes holds the sizes of the field types whose values are stored in
+// binary form.
+var binarySizes = map[byte]int{
+	'I': 4,
+	'O': 8,
+	'@': 8,
+	'T': 8,
+	'Y': 8,
+}
+
+// isBinary reports whether values of field f are stored in binary form.
+func isBinary(f Field) bool {
+	_, ok := binarySizes[f.Fieldtype]
+	return ok
+}
+
+// decodeValue converts the raw bytes of a value of field f into a Go value.
+// Blank values are returned as nil. Character fields are returned as string,
+// numeric fields ('N', 'F', 'I') as int64 if they have no decimals or float64
+// otherwise, double ('O') and currency ('Y') fields as float64, logical
+// fields as bool and date ('D') and timestamp ('@', 'T') fields as
+// time.Time.
+func decodeValue(f Field, raw []byte) (interface{}, error) {
+	if n, ok := binarySizes[f.Fieldtype]; ok && len(raw) != n {
+		return nil, fmt.Errorf("Invalid size %d of binary field %s", len(raw), f)
+	}
+	s := strings.Trim(string(raw), " \x00")
+	switch f.Fieldtype {
+	case 'C':
+		return strings.TrimRight(string(raw), " \x00"), nil
+	case 'N', 'F':
+		if s == "" {
+			return nil, nil
+		}
+		if f.Precision == 0 {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid numeric value %q in field %s", s, f)
+		}
+		return v, nil
+	case 'D':
+		if s == "" {
+			return nil, nil
+		}
+		t, err := time.Parse("20060102", s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid date %q in field %s", s, f)
+		}
+		return t, nil
+	case 'L':
+		switch s {
+		case "T", "t", "Y", "y":
+			return true, nil
+		case "F", "f", "N", "n":
+			return false, nil
+		case "", "?":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Invalid logical value %q in field %s", s, f)
+	case 'I':
+		return int64(int32(binary.LittleEndian.Uint32(raw))), nil
+	case 'O':
+		return math.Float64frombits(binary.LittleEndian.Uint64(raw)), nil
+	case 'Y':
+		return float64(int64(binary.LittleEndian.Uint64(raw))) / 10000, nil
+	case '@', 'T':
+		day := int64(binary.LittleEndian.Uint32(raw[:4]))
+		ms := int64(binary.LittleEndian.Uint32(raw[4:]))
+		if day == 0 && ms == 0 {
+			return nil, nil
+		}
+		t := time.Unix((day-julianUnixEpoch)*86400, ms*int64(time.Millisecond))
+		return t.UTC(), nil
+	default:
+		return s, nil
+	}
+}
+
+// formatValue returns the raw bytes of a value of field f as a string. Values
+// that are stored in binary form are decoded and formatted first.
+func formatValue(f Field, raw []byte) string {
+	if !isBinary(f) {
+		return strings.Trim(string(raw), " ")
+	}
+	v, err := decodeValue(f, raw)
+	if err != nil || v == nil {
+		return ""
+	}
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if f.Fieldtype == 'Y' {
+			return strconv.FormatFloat(v, 'f', 4, 64)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.Format("20060102150405")
+	}
+	return fmt.Sprint(v)
+}
+
+// Overflow selects how floating point numbers are written that do not fit
+// into a numeric ('N' or 'F') field with the precision of the field.
+type Overflow int
+
+const (
+	// OverflowError causes an error, this is the default.
+	OverflowError Overflow = iota
+	// OverflowRound writes the number with fewer decimals.
+	OverflowRound
+	// OverflowScientific is like OverflowRound, but uses scientific notation
+	// if the number does not fit without decimals either.
+	OverflowScientific
+)
+
+// blankValue returns the raw bytes of a blank value of field f: spaces, or
+// zeros for fields in binary form.
+func blankValue(f Field) []byte {
+	if isBinary(f) || binaryMemo(f) {
+		return make([]byte, f.Size)
+	}
+	return bytes.Repeat([]byte(" "), int(f.Size))
+}
+
+// encodeValue converts value into the raw bytes for field f. Supported types
+// are int, int64, float64, string, bool and time.Time, but not all of them
+// can be stored in every type of field. A nil value is written as blank
+// value. Numbers that do not fit into numeric fields are handled as
+// selected by overflow.
+func encodeValue(f Field, value interface{}, overflow Overflow) ([]byte, error) {
+	if value == nil {
+		return blankValue(f), nil
+	}
+	switch f.Fieldtype {
+	case 'N', 'F':
+		return encodeNumber(f, value, overflow)
+	case 'L':
+		if v, ok := value.(bool); ok {
+			if v {
+				return []byte("T"), nil
+			}
+			return []byte("F"), nil
+		}
+	case 'I':
+		if v, ok := toInt64(value); ok {
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, fmt.Errorf("Value %d does not fit into 32-bit integer field %s", v, f)
+			}
+			buf := make([]byte, 4)
+			binary.LittleEndian.PutUint32(buf, uint32(int32(v)))
+			return buf, nil
+		}
+	case 'O':
+		if v, ok := toFloat64(value); ok {
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+			return buf, nil
+		}
+	case 'Y':
+		if v, ok := toFloat64(value); ok {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return blankValue(f), nil
+			}
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, uint64(int64(math.Round(v*10000))))
+			return buf, nil
+		}
+	case '@', 'T':
+		if v, ok := value.(time.Time); ok {
+			v = v.UTC()
+			day := v.Unix()/86400 + julianUnixEpoch
+			if v.Unix() < 0 && v.Unix()%86400 != 0 {
+				day-- // round towards the beginning of the day
+			}
+			midnight := time.Unix((day-julianUnixEpoch)*86400, 0)
+			ms := v.Sub(midnight) / time.Millisecond
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint32(buf[:4], uint32(day))
+			binary.LittleEndian.PutUint32(buf[4:], uint32(ms))
+			return buf, nil
+		}
+	case 'D':
+		switch v := value.(type) {
+		case time.Time:
+			return []byte(v.Format("20060102")), nil
+		case string:
+			return []byte(v), nil
+		}
+	default:
+		switch v := value.(type) {
+		case int:
+			return []byte(strconv.Itoa(v)), nil
+		case int64:
+			return []byte(strconv.FormatInt(v, 10)), nil
+		case float64:
+			return []byte(strconv.FormatFloat(v, 'f', int(f.Precision), 64)), nil
+		case string:
+			return []byte(v), nil
+		case bool:
+			if v {
+				return []byte("T"), nil
+			}
+			return []byte("F"), nil
+		case time.Time:
+			return []byte(v.Format("20060102")), nil
+		}
+	}
+	return nil, fmt.Errorf("Unsupported value type for field %s of type %c: %T", f, f.Fieldtype, value)
+}
+
+// encodeNumber converts value into the raw bytes for the numeric field f.
+// Numbers are right-aligned. NaN and infinite values are written as blank
+// values. Integers must fit into fields without decimals, floating point
+// numbers that do not fit are handled as selected by overflow. Strings are
+// written as they are.
+func encodeNumber(f Field, value interface{}, overflow Overflow) ([]byte, error) {
+	size := int(f.Size)
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case bool:
+		s = "F"
+		if v {
+			s = "T"
+		}
+	case time.Time:
+		s = v.Format("20060102")
+	default:
+		if i, ok := toInt64(value); ok && f.Precision == 0 {
+			s = strconv.FormatInt(i, 10)
+			if len(s) > size {
+				return nil, fmt.Errorf("Value %d does not fit into field %s of size %d", i, f, size)
+			}
+			break
+		}
+		x, ok := toFloat64(value)
+		if !ok {
+			return nil, fmt.Errorf("Unsupported value type for field %s of type %c: %T", f, f.Fieldtype, value)
+		}
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return blankValue(f), nil
+		}
+		s = formatNumber(x, int(f.Precision), size, overflow)
+		if s == "" {
+			return nil, fmt.Errorf("Value %v does not fit into field %s of size %d", x, f, size)
+		}
+	}
+	if len(s) > size {
+		return []byte(s), nil // rejected by the caller
+	}
+	return []byte(fmt.Sprintf("%*s", size, s)), nil
+}
+
+// formatNumber formats v with the given number of decimals. If the result is
+// longer than size, fewer decimals or scientific notation are used depending
+// on overflow. It returns the empty string if v cannot be made to fit.
+func formatNumber(v float64, decimals int, size int, overflow Overflow) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if len(s) <= size || overflow == OverflowError {
+		if len(s) > size {
+			return ""
+		}
+		return s
+	}
+	for d := decimals - 1; d >= 0; d-- {
+		if s := strconv.FormatFloat(v, 'f', d, 64); len(s) <= size {
+			return s
+		}
+	}
+	if overflow == OverflowScientific {
+		for d := decimals; d >= 0; d-- {
+			if s := strconv.FormatFloat(v, 'e', d, 64); len(s) <= size {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int32:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	if v, ok := toInt64(value); ok {
+		return float64(v), true
+	}
+	return 0, false
+}
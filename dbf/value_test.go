@@ -0,0 +1,133 @@
+package dbf
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTypedFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-dbf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "typed.dbf")
+	fields := []Field{
+		LogicalField("FLAG"),
+		IntegerField("INT"),
+		DoubleField("DOUBLE"),
+		TimestampField("STAMP"),
+		DateTimeField("DATETIME"),
+		CurrencyField("PRICE"),
+	}
+	stamp := time.Date(2018, 7, 14, 13, 45, 30, int(250*time.Millisecond), time.UTC)
+	old := time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC)
+	rows := [][]interface{}{
+		{true, int64(-42), 3.25, stamp, old, 19.99},
+		{false, 2147483647, -1e100, old, stamp, int64(-5)},
+	}
+	want := [][]interface{}{
+		{true, int64(-42), 3.25, stamp, old, 19.99},
+		{false, int64(2147483647), -1e100, old, stamp, -5.0},
+		{nil, int64(0), 0.0, nil, nil, 0.0},
+	}
+	wantStrings := [][]string{
+		{"T", "-42", "3.25", "20180714134530", "19691231230000", "19.9900"},
+		{"F", "2147483647", "-1e+100", "19691231230000", "20180714134530", "-5.0000"},
+		{"", "0", "0", "", "", "0.0000"},
+	}
+
+	// no variant supports all of these types
+	if _, err := Create(filename, fields); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("got error %v, want ErrVersionConflict", err)
+	}
+	w, err := Create(filename, fields, Version(DBase7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, values := range rows {
+		row, _ := w.AddRecord()
+		for field, v := range values {
+			if err := w.WriteAttribute(row, field, v); err != nil {
+				t.Fatalf("field %d: %v", field, err)
+			}
+		}
+	}
+	w.AddRecord()
+	if err := w.WriteAttribute(0, 1, int64(1)<<40); err == nil {
+		t.Error("wrote too large value into integer field without error")
+	}
+	if err := w.WriteAttribute(0, 0, "yes"); err == nil {
+		t.Error("wrote string into logical field without error")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for r.Next() {
+		for field := range fields {
+			got, err := r.Value(field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := want[r.Row()][field]; !reflect.DeepEqual(got, want) {
+				t.Errorf("row %d, field %d: got %#v, want %#v", r.Row(), field, got, want)
+			}
+			if got, want := r.Attribute(field), wantStrings[r.Row()][field]; got != want {
+				t.Errorf("row %d, field %d: got string %q, want %q", r.Row(), field, got, want)
+			}
+		}
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+}
+
+func TestEncodeNumber(t *testing.T) {
+	tests := []struct {
+		field    Field
+		value    interface{}
+		overflow Overflow
+		want     string
+		wantErr  bool
+	}{
+		{NumberField("N", 6), 42, OverflowError, "    42", false},
+		{NumberField("N", 6), int64(-42), OverflowError, "   -42", false},
+		{NumberField("N", 3), 1234, OverflowScientific, "", true},
+		{NumberField("N", 4), 2.6, OverflowError, "   3", false},
+		{FloatField("F", 8, 2), 3, OverflowError, "    3.00", false},
+		{FloatField("F", 8, 2), 1.005e3, OverflowError, " 1005.00", false},
+		{FloatField("F", 6, 3), 123.456, OverflowError, "", true},
+		{FloatField("F", 6, 3), 123.456, OverflowRound, "123.46", false},
+		{FloatField("F", 6, 3), 1234567.0, OverflowRound, "", true},
+		{FloatField("F", 6, 3), 1234567.0, OverflowScientific, " 1e+06", false},
+		{FloatField("F", 8, 2), math.NaN(), OverflowError, "        ", false},
+		{FloatField("F", 8, 2), math.Inf(-1), OverflowError, "        ", false},
+		{FloatField("F", 8, 2), nil, OverflowError, "        ", false},
+		{FloatField("F", 8, 2), float32(0.5), OverflowError, "    0.50", false},
+		{FloatField("F", 8, 2), "1.5", OverflowError, "     1.5", false},
+		{CurrencyField("Y"), math.NaN(), OverflowError, "\x00\x00\x00\x00\x00\x00\x00\x00", false},
+		{StringField("C", 4), nil, OverflowError, "    ", false},
+	}
+	for _, test := range tests {
+		buf, err := encodeValue(test.field, test.value, test.overflow)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v in %c(%d, %d): got error %v", test.value, test.field.Fieldtype, test.field.Size, test.field.Precision, err)
+			continue
+		}
+		if err == nil && string(buf) != test.want {
+			t.Errorf("%v in %c(%d, %d): got %q, want %q", test.value, test.field.Fieldtype, test.field.Size, test.field.Precision, buf, test.want)
+		}
+	}
+}
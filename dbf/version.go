@@ -0,0 +1,95 @@
+package dbf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Version bytes of the DBF variants that are written by this package.
+const (
+	// DBase3 is a dBase III table without memo file.
+	DBase3 byte = 0x03
+	// DBase3Memo is a dBase III table with a .dbt memo file.
+	DBase3Memo byte = 0x83
+	// DBase7 is a dBase 7 table without memo file. Its field descriptors
+	// differ from the older variants.
+	DBase7 byte = 0x04
+	// DBase7Memo is a dBase 7 table with a dBase IV style .dbt memo file.
+	DBase7Memo byte = 0x8c
+	// VisualFoxPro is a Visual FoxPro table. Its memo file, if any, is an
+	// .fpt file.
+	VisualFoxPro byte = 0x30
+)
+
+// ErrVersionConflict is returned when a table is created with fields that
+// no single variant supports, e.g. timestamp ('@') and datetime ('T')
+// fields, unless the version is set explicitly.
+var ErrVersionConflict = errors.New("no DBF variant supports all field types")
+
+// isLevel7 reports whether the DBF version byte v belongs to a dBase 7
+// table, which uses 48-byte field descriptors.
+func isLevel7(v byte) bool {
+	return v&0x07 == 0x04
+}
+
+// isVisualFoxPro reports whether the DBF version byte v belongs to a Visual
+// FoxPro table, which has a backlink in the header and stores the block
+// numbers of memos in binary form.
+func isVisualFoxPro(v byte) bool {
+	return v == VisualFoxPro || v == 0x31 || v == 0x32
+}
+
+// defaultVersion returns the version byte of the oldest variant that
+// supports all types of fields: dBase 7 for timestamp ('@') and double ('O')
+// fields, Visual FoxPro for datetime ('T'), currency ('Y') and integer ('I')
+// fields, and dBase III otherwise. Fields that are only supported by dBase 7
+// cannot be mixed with fields that are only supported by Visual FoxPro.
+func defaultVersion(fields []Field) (byte, error) {
+	var level7, foxPro string
+	for _, f := range fields {
+		switch f.Fieldtype {
+		case '@', 'O':
+			level7 = f.String()
+		case 'T', 'Y':
+			foxPro = f.String()
+		}
+	}
+	if level7 != "" && foxPro != "" {
+		return 0, fmt.Errorf("%w: fields %s and %s, set the Version explicitly", ErrVersionConflict, level7, foxPro)
+	}
+	version := DBase3
+	for _, f := range fields {
+		switch f.Fieldtype {
+		case '@', 'O':
+			version = DBase7
+		case 'T', 'Y', 'I':
+			if version == DBase3 {
+				version = VisualFoxPro
+			}
+		}
+	}
+	if hasMemo(fields) {
+		switch version {
+		case DBase3:
+			version = DBase3Memo
+		case DBase7:
+			version = DBase7Memo
+		}
+	}
+	return version, nil
+}
+
+// headerLength returns the length of the header of a table with version v
+// and n fields.
+func headerLength(v byte, n int) int {
+	switch {
+	case isLevel7(v):
+		// language driver name and reserved bytes after the header,
+		// 48-byte field descriptors
+		return 32 + 36 + n*48 + 1
+	case isVisualFoxPro(v):
+		// 263-byte backlink to a database container after the terminator
+		return 32 + n*32 + 1 + 263
+	}
+	return 32 + n*32 + 1
+}
@@ -0,0 +1,127 @@
+package dbf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHeaderMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-dbf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "meta.dbf")
+
+	date := time.Date(2019, 7, 21, 0, 0, 0, 0, time.UTC)
+	w, err := Create(filename, []Field{StringField("NAME", 5)}, Date(date), LanguageDriver(0x57))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.AddRecord()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := r.Header()
+	r.Close()
+	if h.Version != DBase3 || !h.LastUpdate.Equal(date) || h.LanguageDriver != 0x57 {
+		t.Errorf("got version %#x, date %v and language driver %#x", h.Version, h.LastUpdate, h.LanguageDriver)
+	}
+
+	// appending keeps the language driver and updates the date
+	w, err = Append(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	r, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = r.Header()
+	r.Close()
+	if y, m, d := time.Now().Date(); h.LastUpdate != time.Date(y, m, d, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("got date %v after appending, want today", h.LastUpdate)
+	}
+	if h.LanguageDriver != 0x57 {
+		t.Errorf("got language driver %#x after appending, want 0x57", h.LanguageDriver)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   Field
+		opts    []Option
+		version byte
+		memo    string
+	}{
+		{"dbase3", LogicalField("FLAG"), nil, DBase3Memo, "dbase3.dbt"},
+		{"dbase7", TimestampField("STAMP"), nil, DBase7Memo, "dbase7.dbt"},
+		{"foxpro", CurrencyField("PRICE"), nil, VisualFoxPro, "foxpro.fpt"},
+		{"forced", LogicalField("FLAG"), []Option{Version(VisualFoxPro)}, VisualFoxPro, "forced.fpt"},
+	}
+	dir, err := ioutil.TempDir("", "go-shp-dbf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range tests {
+		filename := filepath.Join(dir, test.name+".dbf")
+		fields := []Field{StringField("NAME", 10), test.field, MemoField("NOTES")}
+		w, err := Create(filename, fields, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row, _ := w.AddRecord()
+		w.WriteAttribute(row, 0, "first")
+		w.WriteAttribute(row, 2, "a note")
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		w, err = Append(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row, _ = w.AddRecord()
+		w.WriteAttribute(row, 0, "second")
+		w.WriteAttribute(row, 2, "another note")
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, test.memo)); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		r, err := Open(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if v := r.Header().Version; v != test.version {
+			t.Errorf("%s: got version %#x, want %#x", test.name, v, test.version)
+		}
+		if n := len(r.Fields()); n != 3 || r.Fields()[1].String() != test.field.String() {
+			t.Errorf("%s: got fields %v", test.name, r.Fields())
+		}
+		want := [][]string{{"first", "a note"}, {"second", "another note"}}
+		for r.Next() {
+			if got := r.Attribute(0); got != want[r.Row()][0] {
+				t.Errorf("%s, row %d: got name %q, want %q", test.name, r.Row(), got, want[r.Row()][0])
+			}
+			if got := r.Attribute(2); got != want[r.Row()][1] {
+				t.Errorf("%s, row %d: got memo %q, want %q", test.name, r.Row(), got, want[r.Row()][1])
+			}
+		}
+		if err := r.Err(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,434 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Writer writes a DBF table. The records are added with AddRecord and filled
+// with WriteAttribute. It is important to use Close when done because that
+// method writes the header.
+type Writer struct {
+	w            io.WriteSeeker
+	fields       []Field
+	headerLength int
+	recordLength int
+	num          int
+	options
+
+	memo *memoWriter // nil if there is no memo file
+}
+
+// Option configures the header of a table that is written by a Writer.
+type Option func(*options)
+
+// options holds the metadata that is written to the header.
+type options struct {
+	version        byte
+	languageDriver byte
+	date           time.Time // the current date if zero
+	overflow       Overflow
+}
+
+// newOptions applies opts to the defaults for a table with the given fields.
+// Without a Version option, the version is chosen by defaultVersion.
+func newOptions(fields []Field, opts []Option) (options, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.version != 0 {
+		return o, nil
+	}
+	var err error
+	o.version, err = defaultVersion(fields)
+	return o, err
+}
+
+// Version sets the version byte of the table, e.g. DBase3 or VisualFoxPro.
+// By default, the oldest variant that supports the types of all fields is
+// chosen. If there is none, e.g. for timestamp ('@') and datetime ('T')
+// fields, the version must be set. The version also determines the format
+// of the memo file.
+func Version(v byte) Option {
+	return func(o *options) {
+		o.version = v
+	}
+}
+
+// LanguageDriver sets the language driver byte of the table, which
+// identifies its code page, e.g. 0x57 for ANSI (Windows-1252). By default,
+// it is 0.
+func LanguageDriver(b byte) Option {
+	return func(o *options) {
+		o.languageDriver = b
+	}
+}
+
+// Date sets the date of the last update that is written to the header.
+// By default, the current date is used. Setting a fixed date allows to
+// create identical files in reproducible builds.
+func Date(t time.Time) Option {
+	return func(o *options) {
+		o.date = t
+	}
+}
+
+// NumberOverflow selects how floating point numbers are written that do not
+// fit into numeric fields. By default, WriteAttribute returns an error.
+func NumberOverflow(o Overflow) Option {
+	return func(o2 *options) {
+		o2.overflow = o
+	}
+}
+
+// Create creates the DBF file at filename with the given fields. If there are
+// memo fields, the memo file with the same name is created as well, which
+// has the extension .fpt for FoxPro tables and .dbt otherwise.
+func Create(filename string, fields []Field, opts ...Option) (*Writer, error) {
+	if err := CheckFields(fields); err != nil {
+		return nil, err
+	}
+	o, err := newOptions(fields, opts)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	var memo *os.File
+	if hasMemo(fields) {
+		ext := ".dbt"
+		if isFoxPro(o.version) {
+			ext = ".fpt"
+		}
+		memo, err = os.Create(strings.TrimSuffix(filename, filepath.Ext(filename)) + ext)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	var w *Writer
+	if memo != nil {
+		w, err = NewWriterWithMemo(f, memo, fields, opts...)
+	} else {
+		w, err = NewWriter(f, fields, opts...)
+	}
+	if err != nil {
+		f.Close()
+		if memo != nil {
+			memo.Close()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWriter returns a Writer that writes a DBF table with the given fields to
+// ws. If ws implements io.Closer, it is closed by Close. It returns an error
+// if the field names are invalid or not unique, see CheckFields.
+func NewWriter(ws io.WriteSeeker, fields []Field, opts ...Option) (*Writer, error) {
+	if err := CheckFields(fields); err != nil {
+		return nil, err
+	}
+	return newWriter(ws, fields, opts)
+}
+
+// newWriter is like NewWriter, but does not check the field names.
+func newWriter(ws io.WriteSeeker, fields []Field, opts []Option) (*Writer, error) {
+	o, err := newOptions(fields, opts)
+	if err != nil {
+		return nil, err
+	}
+	if isVisualFoxPro(o.version) {
+		// the block numbers of memos are stored as 4-byte binary numbers
+		fields = append([]Field(nil), fields...)
+		for i := range fields {
+			if fields[i].Fieldtype == 'M' {
+				fields[i].Size = 4
+			}
+		}
+	}
+	w := &Writer{
+		w:       ws,
+		fields:  fields,
+		options: o,
+	}
+
+	// calculate record length
+	w.recordLength = 1
+	for _, field := range w.fields {
+		w.recordLength += int(field.Size)
+	}
+	if w.recordLength > 0xffff {
+		return nil, fmt.Errorf("DBF record length %d exceeds maximum", w.recordLength)
+	}
+
+	w.headerLength = headerLength(w.version, len(w.fields))
+
+	// fill header space with empty bytes for now
+	if _, err := ws.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := ws.Write(make([]byte, w.headerLength)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWriterWithMemo is like NewWriter, but writes the contents of memo fields
+// into the memo file memo, whose format is chosen by the version of the
+// table. If memo implements io.Closer, it is closed by Close.
+func NewWriterWithMemo(ws, memo io.WriteSeeker, fields []Field, opts ...Option) (*Writer, error) {
+	w, err := NewWriter(ws, fields, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if w.memo, err = newMemoWriter(memo, w.version); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append opens the DBF file at filename for adding records to it. The
+// version and language driver of the table are kept, the date of the last
+// update is set to the current date unless it is set with opts. An existing
+// memo file is opened as well.
+func Append(filename string, opts ...Option) (*Writer, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	h, fields, err := readHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	o := options{version: h.Version, languageDriver: h.LanguageDriver}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	w := &Writer{
+		w:            f,
+		fields:       fields,
+		headerLength: h.HeaderLength,
+		recordLength: h.RecordLength,
+		num:          h.NumRecords,
+		options:      o,
+	}
+	ext := ".dbt"
+	if isFoxPro(h.Version) {
+		ext = ".fpt"
+	}
+	if name := memoFilename(filename, ext); hasMemo(fields) && name != "" {
+		m, err := os.OpenFile(name, os.O_RDWR, 0666)
+		if err == nil {
+			if w.memo, err = appendMemoWriter(m, h.Version); err != nil {
+				m.Close()
+			}
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Fields returns the fields of the table.
+func (w *Writer) Fields() []Field {
+	return w.fields
+}
+
+// NumRecords returns the number of records in the table.
+func (w *Writer) NumRecords() int {
+	return w.num
+}
+
+func (w *Writer) recordOffset(row int) int64 {
+	return int64(w.headerLength) + int64(row)*int64(w.recordLength)
+}
+
+// AddRecord adds an empty record to the end of the table and returns its
+// index. The first byte of the record is a space that indicates a valid
+// record, all values are blank, or zero for fields in binary form.
+func (w *Writer) AddRecord() (int, error) {
+	if err := w.writeRecord(w.emptyRecord()); err != nil {
+		return 0, err
+	}
+	return w.num - 1, nil
+}
+
+// writeRecord adds the record rec to the end of the table.
+func (w *Writer) writeRecord(rec []byte) error {
+	if _, err := w.w.Seek(w.recordOffset(w.num), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(rec); err != nil {
+		return err
+	}
+	w.num++
+	return nil
+}
+
+// emptyRecord returns the bytes of a record with blank values.
+func (w *Writer) emptyRecord() []byte {
+	buf := make([]byte, w.recordLength)
+	for i := range buf {
+		buf[i] = ' '
+	}
+	offset := 1
+	for _, f := range w.fields {
+		copy(buf[offset:offset+int(f.Size)], blankValue(f))
+		offset += int(f.Size)
+	}
+	return buf
+}
+
+// WriteAttribute writes value for field into the given row in the table. The
+// field value corresponds to the field in the slice used to create the
+// table. The value can be an int, int64, float64, string, bool or
+// time.Time, but it must suit the type of the field, e.g. bool for 'L' and
+// time.Time for '@' and 'T' fields. Numbers are right-aligned in numeric
+// fields. A nil value, as well as NaN and infinite numbers in numeric
+// fields, are written as blank values.
+func (w *Writer) WriteAttribute(row int, field int, value interface{}) error {
+	if row < 0 || row >= w.num {
+		return fmt.Errorf("DBF row %d out of range", row)
+	}
+	if field < 0 || field >= len(w.fields) {
+		return fmt.Errorf("DBF field %d out of range", field)
+	}
+	var buf []byte
+	var err error
+	if w.fields[field].Fieldtype == 'M' {
+		buf, err = w.encodeMemo(w.fields[field], value)
+	} else {
+		buf, err = encodeValue(w.fields[field], value, w.overflow)
+	}
+	if err != nil {
+		return err
+	}
+
+	if sz := int(w.fields[field].Size); len(buf) > sz {
+		return fmt.Errorf("Unable to write field %v: %q exceeds field length %v", field, buf, sz)
+	}
+	// overwrite the whole old value, which may be longer
+	raw := blankValue(w.fields[field])
+	copy(raw, buf)
+
+	seekTo := 1 + int64(w.headerLength) + (int64(row) * int64(w.recordLength))
+	for n := 0; n < field; n++ {
+		seekTo += int64(w.fields[n].Size)
+	}
+	if _, err := w.w.Seek(seekTo, io.SeekStart); err != nil {
+		return err
+	}
+	return binary.Write(w.w, binary.LittleEndian, raw)
+}
+
+// Delete marks the record at row as deleted. The record stays in the table
+// until it is removed by Pack.
+func (w *Writer) Delete(row int) error {
+	if row < 0 || row >= w.num {
+		return fmt.Errorf("DBF row %d out of range", row)
+	}
+	if _, err := w.w.Seek(w.recordOffset(row), io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.w.Write([]byte{'*'})
+	return err
+}
+
+// encodeMemo writes value into the memo file and returns the raw bytes of the
+// block number for field f.
+func (w *Writer) encodeMemo(f Field, value interface{}) ([]byte, error) {
+	v, ok := value.(string)
+	if value == nil {
+		v, ok = "", true
+	}
+	if !ok {
+		return nil, fmt.Errorf("Unsupported value type for memo field %s: %T", f, value)
+	}
+	if w.memo == nil {
+		return nil, fmt.Errorf("No memo file for memo field %s", f)
+	}
+	if v == "" {
+		return blankValue(f), nil
+	}
+	block, err := w.memo.write(v)
+	if err != nil {
+		return nil, err
+	}
+	if binaryMemo(f) {
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, block)
+		return buf, nil
+	}
+	return []byte(fmt.Sprintf("%*d", f.Size, block)), nil
+}
+
+// writeHeader writes the DBF header to the beginning of the file.
+func (w *Writer) writeHeader() error {
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	date := w.date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	// version, year (YEAR-1900), month, day
+	binary.Write(w.w, binary.LittleEndian, []byte{w.version, byte(date.Year() - 1900), byte(date.Month()), byte(date.Day())})
+	// number of records
+	binary.Write(w.w, binary.LittleEndian, uint32(w.num))
+	// header length, record length
+	binary.Write(w.w, binary.LittleEndian, []uint16{uint16(w.headerLength), uint16(w.recordLength)})
+	// padding with language driver at byte 29
+	padding := make([]byte, 20)
+	padding[17] = w.languageDriver
+	binary.Write(w.w, binary.LittleEndian, padding)
+
+	if isLevel7(w.version) {
+		// language driver name and reserved bytes
+		binary.Write(w.w, binary.LittleEndian, make([]byte, 36))
+		for _, field := range w.fields {
+			binary.Write(w.w, binary.LittleEndian, field.level7())
+		}
+	} else {
+		for _, field := range w.fields {
+			binary.Write(w.w, binary.LittleEndian, field)
+		}
+	}
+
+	// end with return
+	_, err := w.w.Write([]byte("\r"))
+	return err
+}
+
+// Close writes the header of the table and closes the underlying writer if
+// it implements io.Closer.
+func (w *Writer) Close() error {
+	if w.w == nil {
+		return errors.New("DBF writer already closed")
+	}
+	err := w.writeHeader()
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if w.memo != nil {
+		if merr := w.memo.close(); err == nil {
+			err = merr
+		}
+	}
+	w.w = nil
+	return err
+}
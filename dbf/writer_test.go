@@ -0,0 +1,77 @@
+package dbf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type seekTracker struct {
+	io.Writer
+	offset int64
+}
+
+func (s *seekTracker) Seek(offset int64, whence int) (int64, error) {
+	s.offset = offset
+	return s.offset, nil
+}
+
+func (s *seekTracker) Close() error {
+	return nil
+}
+
+func TestWriteAttribute(t *testing.T) {
+	buf := new(bytes.Buffer)
+	s := &seekTracker{Writer: buf}
+	w := Writer{
+		w: s,
+		fields: []Field{
+			StringField("A_STRING", 6),
+			FloatField("A_FLOAT", 8, 4),
+			NumberField("AN_INT", 4),
+		},
+		recordLength: 100,
+		num:          4,
+	}
+
+	tests := []struct {
+		name       string
+		row        int
+		field      int
+		data       interface{}
+		wantOffset int64
+		wantData   string
+	}{
+		{"string-0", 0, 0, "test", 1, "test  "},
+		{"string-0-overflow-1", 0, 0, "overflo", 0, ""},
+		{"string-0-overflow-n", 0, 0, "overflowing", 0, ""},
+		{"string-3", 3, 0, "things", 301, "things"},
+		{"float-0", 0, 1, 123.44, 7, "123.4400"},
+		{"float-0-overflow-1", 0, 1, 1234.0, 0, ""},
+		{"float-0-overflow-n", 0, 1, 123456789.0, 0, ""},
+		{"int-0", 0, 2, 4242, 15, "4242"},
+		{"int-0-overflow-1", 0, 2, 42424, 0, ""},
+		{"int-0-overflow-n", 0, 2, 42424343, 0, ""},
+		{"row-negative", -1, 0, "test", 0, ""},
+		{"row-out-of-range", 4, 0, "test", 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			s.offset = 0
+
+			err := w.WriteAttribute(test.row, test.field, test.data)
+
+			if buf.String() != test.wantData {
+				t.Errorf("got data: %v, want: %v", buf.String(), test.wantData)
+			}
+			if s.offset != test.wantOffset {
+				t.Errorf("got seek offset: %v, want: %v", s.offset, test.wantOffset)
+			}
+			if err == nil && test.wantData == "" {
+				t.Error("got no data and no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package shp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedShapeType is the error of a FormatError for a shape
+	// whose type is not defined by the specification.
+	ErrUnsupportedShapeType = errors.New("unsupported shape type")
+	// ErrShapeTypeMismatch is returned when writing a shape whose type
+	// differs from the type of the shapefile. With EnforceShapeType, it is
+	// also the error of a FormatError for such a record.
+	ErrShapeTypeMismatch = errors.New("shape type mismatch")
+	// ErrTruncated is the error of a FormatError for a header or record
+	// that ends before all of its content could be read.
+	ErrTruncated = errors.New("truncated file")
+	// ErrInvalidContentLength is the error of a FormatError for a record
+	// whose content length is too small to hold a shape type.
+	ErrInvalidContentLength = errors.New("invalid content length")
+	// ErrRecordMismatch is the error of a FormatError if the DBF table has
+	// fewer records than there are shapes.
+	ErrRecordMismatch = errors.New("DBF table has fewer records than shapes")
+	// ErrNoDBF is returned when attributes are accessed or written while
+	// there is no DBF table, e.g. before SetFields has been called.
+	ErrNoDBF = errors.New("no DBF table")
+	// ErrFileNotFound is returned if a file of a shapefile is missing in an
+	// archive.
+	ErrFileNotFound = errors.New("no such file in archive")
+	// ErrMultipleShapes is returned if an archive contains more than one
+	// shapefile, but a single one was expected.
+	ErrMultipleShapes = errors.New("archive contains multiple .shp files")
+)
+
+// FormatError describes a problem with the contents of one of the files of a
+// shapefile. Use errors.Is to test for the cause, e.g. ErrTruncated, and
+// errors.As to access the position.
+type FormatError struct {
+	// File is the kind of file: "shp", "shx" or "dbf".
+	File string
+	// Offset is the position in the file of the record or header in which
+	// the problem was found, or -1 if it is unknown.
+	Offset int64
+	// Record is the number of the record as stored in the file, starting at
+	// 1, or 0 if the problem is in the header.
+	Record int
+	// Err is the cause of the problem.
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	s := "Invalid " + strings.ToUpper(e.File) + " file"
+	if e.Record > 0 {
+		s += fmt.Sprintf(" in record %d", e.Record)
+	}
+	if e.Offset >= 0 {
+		s += fmt.Sprintf(" at offset %d", e.Offset)
+	}
+	return s + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the problem.
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// mismatch returns the error for a record of type t in a shapefile of type
+// header.
+func mismatch(t, header ShapeType) error {
+	return fmt.Errorf("%w: %v record in %v shapefile", ErrShapeTypeMismatch, t, header)
+}
+
+// truncated marks err as ErrTruncated if it signals an unexpected end of the
+// file. Other errors are returned unchanged.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %v", ErrTruncated, err)
+	}
+	return err
+}
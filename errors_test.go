@@ -0,0 +1,114 @@
+package shp
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/jonas-p/go-shp/dbf"
+)
+
+func TestFormatErrors(t *testing.T) {
+	valid := polyLineRecord(1, 3, []int32{0}, []Point{{0, 0}, {1, 1}, {2, 2}})
+	unsupported := []byte{0, 0, 0, 1, 0, 0, 0, 2, 255, 255, 255, 255}
+	tests := []struct {
+		name   string
+		record []byte
+		err    error
+		offset int64
+		num    int
+	}{
+		{"truncated shape", valid[:len(valid)-10], ErrTruncated, 0, 1},
+		{"truncated metadata", valid[:6], ErrTruncated, 0, 1},
+		{"second shape truncated", append(valid, valid[:20]...), ErrTruncated, int64(len(valid)), 1},
+		{"unsupported shape type", unsupported, ErrUnsupportedShapeType, 0, 1},
+		{"invalid content length", []byte{0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0}, ErrInvalidContentLength, 0, 1},
+	}
+
+	for _, test := range tests {
+		readers := map[string]interface {
+			Next() bool
+			Err() error
+		}{
+			"reader":    &Reader{shp: newReadSeekCloser(test.record), filelength: int64(len(test.record))},
+			"seqReader": &seqReader{shp: newReadSeekCloser(test.record), filelength: int64(len(test.record))},
+		}
+		for name, r := range readers {
+			for r.Next() {
+			}
+			var fe *FormatError
+			if !errors.As(r.Err(), &fe) || !errors.Is(r.Err(), test.err) {
+				t.Errorf("%s, %s: got error %v, want %v", test.name, name, r.Err(), test.err)
+				continue
+			}
+			if fe.File != "shp" || fe.Offset != test.offset || fe.Record != test.num {
+				t.Errorf("%s, %s: got error in %s record %d at %d, want shp record %d at %d",
+					test.name, name, fe.File, fe.Record, fe.Offset, test.num, test.offset)
+			}
+		}
+	}
+}
+
+func TestTruncatedHeader(t *testing.T) {
+	filename := filenamePrefix + "truncated"
+	defer removeShapefile(filename)
+	if err := ioutil.WriteFile(filename+".shp", make([]byte, 50), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Open(filename + ".shp")
+	var fe *FormatError
+	if !errors.As(err, &fe) || fe.Offset != 0 || fe.Record != 0 {
+		t.Fatalf("got error %v, want FormatError in SHP header", err)
+	}
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("got error %v, want truncated file", err)
+	}
+}
+
+func TestRecordMismatch(t *testing.T) {
+	filename := filenamePrefix + "mismatch"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	w.Write(&Point{2, 2})
+	if err := w.WriteAttribute(0, 0, "x"); !errors.Is(err, ErrNoDBF) {
+		t.Errorf("got error %v when writing without fields, want ErrNoDBF", err)
+	}
+	w.Close()
+
+	// replace the DBF with a table that has a single record
+	d, err := dbf.Create(filename+".dbf", []Field{StringField("NAME", 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.AddRecord()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	shpFile, err := os.Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbfFile, err := os.Open(filename + ".dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := SequentialReaderFromExt(shpFile, dbfFile)
+	defer sr.Close()
+	n := 0
+	for sr.Next() {
+		n++
+	}
+	var fe *FormatError
+	if !errors.As(sr.Err(), &fe) || !errors.Is(sr.Err(), ErrRecordMismatch) {
+		t.Fatalf("got error %v, want ErrRecordMismatch", sr.Err())
+	}
+	if n != 1 || fe.File != "dbf" || fe.Record != 2 {
+		t.Errorf("read %d shapes and got error in %s record %d, want 1 shape and dbf record 2", n, fe.File, fe.Record)
+	}
+}
@@ -12,11 +12,17 @@ type errReader struct {
 	io.Reader
 	e error
 	n int64
+
+	// limit is the number of bytes that may be read, e.g. up to the end of
+	// the current record, or 0 if unknown. It is used together with opts
+	// to validate counts before memory is allocated for them.
+	limit int64
+	opts  readOptions
 }
 
 func (er *errReader) Read(p []byte) (n int, err error) {
 	if er.e != nil {
-		return 0, fmt.Errorf("unable to read after previous error: %v", er.e)
+		return 0, fmt.Errorf("unable to read after previous error: %w", er.e)
 	}
 	n, err = er.Reader.Read(p)
 	if n < len(p) && err != nil {
@@ -25,3 +31,19 @@ func (er *errReader) Read(p []byte) (n int, err error) {
 	er.n += int64(n)
 	return n, er.e
 }
+
+// readContent reads shape from the rest of a record of the given length,
+// whose header has already been read through er. Reads are confined to the
+// record, so a shape that does not fit the content length is reported as
+// ErrInvalidContentLength instead of consuming the next record.
+func readContent(shape Shape, er *errReader, length int64) error {
+	r := er.Reader
+	er.Reader = io.LimitReader(r, length-er.n)
+	er.limit = length
+	shape.read(er)
+	er.Reader = r
+	if er.e != nil && er.n == length {
+		return fmt.Errorf("%w: shape exceeds the record", ErrInvalidContentLength)
+	}
+	return truncated(er.e)
+}
@@ -0,0 +1,92 @@
+package shp
+
+// extent holds the bounding box and the ranges of the Z values and measures
+// of a number of shapes, as they are written to the file headers.
+type extent struct {
+	bbox   Box
+	zRange [2]float64
+	mRange [2]float64
+
+	hasBBox bool // false as long as only null shapes have been added
+	hasZ    bool
+	hasM    bool
+}
+
+// add extends e with the coordinates of shape. Null shapes and measures
+// that signal "no data" are ignored.
+func (e *extent) add(shape Shape) {
+	if shape.ShapeType() == NULL {
+		return
+	}
+	if e.hasBBox {
+		e.bbox.Extend(shape.BBox())
+	} else {
+		e.bbox, e.hasBBox = shape.BBox(), true
+	}
+	z, m := zmValues(shape)
+	for _, v := range z {
+		extendRange(&e.zRange, &e.hasZ, v)
+	}
+	for _, v := range m {
+		if !IsNoData(v) {
+			extendRange(&e.mRange, &e.hasM, v)
+		}
+	}
+}
+
+// hasZValues reports whether shapes of type t have Z values.
+func hasZValues(t ShapeType) bool {
+	switch t {
+	case POINTZ, POLYLINEZ, POLYGONZ, MULTIPOINTZ, MULTIPATCH:
+		return true
+	}
+	return false
+}
+
+// hasMeasureValues reports whether shapes of type t can have measures.
+func hasMeasureValues(t ShapeType) bool {
+	switch t {
+	case POINTM, POLYLINEM, POLYGONM, MULTIPOINTM:
+		return true
+	}
+	return hasZValues(t)
+}
+
+// extendRange extends r, which is empty unless ok is set, with v.
+func extendRange(r *[2]float64, ok *bool, v float64) {
+	if v != v { // NaN
+		return
+	}
+	if !*ok || v < r[0] {
+		r[0] = v
+	}
+	if !*ok || v > r[1] {
+		r[1] = v
+	}
+	*ok = true
+}
+
+// zmValues returns the Z values and measures of shape.
+func zmValues(shape Shape) (z, m []float64) {
+	switch s := shape.(type) {
+	case *PointZ:
+		return []float64{s.Z}, []float64{s.M}
+	case *PolyLineZ:
+		return s.ZArray, s.MArray
+	case *PolygonZ:
+		return s.ZArray, s.MArray
+	case *MultiPointZ:
+		return s.ZArray, s.MArray
+	case *MultiPatch:
+		return s.ZArray, s.MArray
+	case *PointM:
+		return nil, []float64{s.M}
+	case *PolyLineM:
+		return nil, s.MArray
+	case *PolygonM:
+		return nil, s.MArray
+	case *MultiPointM:
+		return nil, s.MArray
+	}
+	return nil, nil
+}
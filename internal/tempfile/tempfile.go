@@ -0,0 +1,50 @@
+// Package tempfile creates temporary files that replace existing files once
+// they have been written completely.
+package tempfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File is a temporary file in the directory of the file that it replaces.
+type File struct {
+	*os.File
+	target string
+}
+
+// Create creates a temporary file for replacing the file at filename. It has
+// the same permissions as filename, which must exist.
+func Create(filename string) (*File, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(fi.Mode().Perm()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &File{f, filename}, nil
+}
+
+// Replace renames the closed file f to the file that it replaces. f is
+// removed if that fails.
+func (f *File) Replace() error {
+	if err := os.Rename(f.Name(), f.target); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Discard closes and removes f.
+func (f *File) Discard() {
+	f.Close()
+	os.Remove(f.Name())
+}
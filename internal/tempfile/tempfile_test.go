@@ -0,0 +1,60 @@
+package tempfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tempfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "table.dbf")
+	if err := ioutil.WriteFile(filename, []byte("old"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Replace(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != orig.Mode() {
+		t.Errorf("got mode %v, want %v", fi.Mode(), orig.Mode())
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != "new" {
+		t.Errorf("got content %q, want %q", b, "new")
+	}
+
+	f, err = Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Discard()
+	if names, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(names) > 0 {
+		t.Errorf("temporary files %v were not removed", names)
+	}
+	if _, err := Create(filepath.Join(dir, "missing.dbf")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
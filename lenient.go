@@ -0,0 +1,113 @@
+package shp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/jonas-p/go-shp/dbf"
+)
+
+// Lenient makes Next skip records that cannot be read, e.g. because of an
+// unsupported shape type, truncated content or an invalid deletion indicator
+// in the DBF table, instead of stopping. The skipped records are reported by
+// Problems. A Reader finds the next record using the SHX file or, if there
+// is none, the content length in the record header. A SequentialReader can
+// only use the content length, so it still stops if that is invalid.
+func Lenient() ReadOption {
+	return func(o *readOptions) {
+		o.lenient = true
+	}
+}
+
+// Problems returns the records that were skipped in lenient mode, in the
+// order in which they were encountered.
+func (r *Reader) Problems() []*FormatError {
+	return r.problems
+}
+
+// Problems returns the records that sr skipped in lenient mode.
+func Problems(sr SequentialReader) []*FormatError {
+	if p, ok := sr.(interface {
+		Problems() []*FormatError
+	}); ok {
+		return p.Problems()
+	}
+	return nil
+}
+
+// skip handles the bad record at cur, which has the given length or -1 if
+// the length is unknown. In strict mode, the problem is returned as error.
+// In lenient mode, the reader is moved to the next record and the problem is
+// returned as bad record.
+func (r *Reader) skip(cur, length int64, err error) (*FormatError, error) {
+	fe := &FormatError{File: "shp", Offset: cur, Record: int(r.num), Err: err}
+	if !r.lenient {
+		return nil, fe
+	}
+	next, ok := r.nextOffset(cur)
+	if !ok {
+		if length < 0 {
+			return nil, fe // there is no way to find the next record
+		}
+		next = cur + length
+	}
+	r.shp.Seek(next, io.SeekStart)
+	return fe, nil
+}
+
+// nextOffset returns the offset of the record that follows the one at cur
+// according to the SHX file, or the length of the file if there is none. It
+// returns false if there is no SHX file.
+func (r *Reader) nextOffset(cur int64) (int64, bool) {
+	if r.offsets == nil {
+		r.loadOffsets()
+	}
+	if len(r.offsets) == 0 {
+		return 0, false
+	}
+	i := sort.Search(len(r.offsets), func(i int) bool {
+		return r.offsets[i] > cur
+	})
+	if i == len(r.offsets) {
+		return r.filelength, true
+	}
+	return r.offsets[i], true
+}
+
+// loadOffsets reads the offsets of all records from the SHX file.
+func (r *Reader) loadOffsets() {
+	r.offsets = []int64{}
+	shx, err := r.open(".shx")
+	if err != nil {
+		return
+	}
+	defer shx.Close()
+	if _, err := shx.Seek(100, io.SeekStart); err != nil {
+		return
+	}
+	b, _ := ioutil.ReadAll(shx)
+	for i := 0; i+8 <= len(b); i += 8 {
+		r.offsets = append(r.offsets, int64(int32(binary.BigEndian.Uint32(b[i:])))*2)
+	}
+	sort.Slice(r.offsets, func(i, j int) bool {
+		return r.offsets[i] < r.offsets[j]
+	})
+}
+
+// checkRow returns the problem with the deletion indicator of the record of
+// the current shape in the DBF table, or nil if there is none.
+func (r *Reader) checkRow() *FormatError {
+	if r.openDbf() != nil {
+		return nil
+	}
+	row := int(r.num) - 1
+	if _, err := r.dbf.IsDeleted(row); errors.Is(err, dbf.ErrDeletionIndicator) {
+		h := r.dbf.Header()
+		return &FormatError{File: "dbf", Offset: int64(h.HeaderLength) + int64(row)*int64(h.RecordLength),
+			Record: row + 1, Err: err}
+	}
+	return nil
+}
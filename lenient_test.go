@@ -0,0 +1,186 @@
+package shp
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLenientRecords(t *testing.T) {
+	valid := polyLineRecord(1, 2, []int32{0}, []Point{{0, 0}, {1, 1}})
+	unsupported := []byte{0, 0, 0, 2, 0, 0, 0, 4, 255, 255, 255, 255, 0, 0, 0, 0}
+	var file []byte
+	file = append(file, valid...)
+	file = append(file, unsupported...)
+	file = append(file, valid...)
+
+	for _, lenient := range []bool{false, true} {
+		var o readOptions
+		if lenient {
+			o = newReadOptions([]ReadOption{Lenient()})
+		}
+		readers := map[string]SequentialReader{
+			"reader":    &Reader{shp: newReadSeekCloser(file), filelength: int64(len(file)), readOptions: o},
+			"seqReader": &seqReader{shp: newReadSeekCloser(file), filelength: int64(len(file)), readOptions: o},
+		}
+		for name, r := range readers {
+			n := 0
+			for r.Next() {
+				n++
+			}
+			problems := Problems(r)
+			if !lenient {
+				if n != 1 || !errors.Is(r.Err(), ErrUnsupportedShapeType) || len(problems) != 0 {
+					t.Errorf("strict %s: read %d shapes with error %v and %d problems, want 1 shape and ErrUnsupportedShapeType",
+						name, n, r.Err(), len(problems))
+				}
+				continue
+			}
+			if n != 2 || r.Err() != nil {
+				t.Errorf("lenient %s: read %d shapes with error %v, want 2", name, n, r.Err())
+			}
+			if len(problems) != 1 || !errors.Is(problems[0], ErrUnsupportedShapeType) ||
+				problems[0].Record != 2 || problems[0].Offset != int64(len(valid)) {
+				t.Errorf("lenient %s: got problems %v, want unsupported shape type in record 2", name, problems)
+			}
+		}
+	}
+}
+
+func TestShapeExceedsRecord(t *testing.T) {
+	valid := polyLineRecord(1, 2, []int32{0}, []Point{{0, 0}, {1, 1}})
+	// a point record with a content length of 2, which only holds the type
+	short := []byte{0, 0, 0, 2, 0, 0, 0, 2, 1, 0, 0, 0}
+	var file []byte
+	file = append(file, valid...)
+	file = append(file, short...)
+	file = append(file, valid...)
+
+	for _, lenient := range []bool{false, true} {
+		var o readOptions
+		if lenient {
+			o = newReadOptions([]ReadOption{Lenient()})
+		}
+		readers := map[string]SequentialReader{
+			"reader":    &Reader{shp: newReadSeekCloser(file), filelength: int64(len(file)), readOptions: o},
+			"seqReader": &seqReader{shp: newReadSeekCloser(file), filelength: int64(len(file)), readOptions: o},
+		}
+		for name, r := range readers {
+			var shapes []Shape
+			for r.Next() {
+				_, s := r.Shape()
+				shapes = append(shapes, s)
+			}
+			if !lenient {
+				if len(shapes) != 1 || !errors.Is(r.Err(), ErrInvalidContentLength) {
+					t.Errorf("strict %s: read %d shapes with error %v, want 1 shape and ErrInvalidContentLength",
+						name, len(shapes), r.Err())
+				}
+				continue
+			}
+			if len(shapes) != 2 || r.Err() != nil {
+				t.Fatalf("lenient %s: read %d shapes with error %v, want 2", name, len(shapes), r.Err())
+			}
+			if p, ok := shapes[1].(*PolyLine); !ok || !reflect.DeepEqual(p.Points, []Point{{0, 0}, {1, 1}}) {
+				t.Errorf("lenient %s: read %+v after the bad record", name, shapes[1])
+			}
+			problems := Problems(r)
+			if len(problems) != 1 || !errors.Is(problems[0], ErrInvalidContentLength) ||
+				problems[0].Offset != int64(len(valid)) {
+				t.Errorf("lenient %s: got problems %v, want invalid content length of record 2", name, problems)
+			}
+		}
+	}
+}
+
+func TestLenientFile(t *testing.T) {
+	filename := filenamePrefix + "lenient"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POLYLINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetFields([]Field{StringField("NAME", 5)})
+	for i := 0; i < 4; i++ {
+		w.Write(NewPolyLine([][]Point{{{0, 0}, {float64(i), 1}}}))
+	}
+	w.Close()
+
+	// corrupt the deletion indicator of the third record
+	d, err := os.OpenFile(filename+".dbf", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.WriteAt([]byte{'?'}, 32+32+1+2*6); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	read := func(sr SequentialReader) ([]int, []*FormatError, error) {
+		defer sr.Close()
+		var rows []int
+		for sr.Next() {
+			row, _ := sr.Shape()
+			rows = append(rows, row)
+		}
+		return rows, Problems(sr), sr.Err()
+	}
+	openSeq := func() SequentialReader {
+		shp, err := os.Open(filename + ".shp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dbf, err := os.Open(filename + ".dbf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return SequentialReaderFromExt(shp, dbf, Lenient())
+	}
+
+	rows, problems, err := read(openSeq())
+	if err != nil || !reflect.DeepEqual(rows, []int{0, 1, 3}) {
+		t.Errorf("sequential: read rows %v with error %v, want [0 1 3]", rows, err)
+	}
+	if len(problems) != 1 || problems[0].File != "dbf" || problems[0].Record != 3 {
+		t.Errorf("sequential: got problems %v, want DBF record 3", problems)
+	}
+
+	// corrupt the content length of the second shape, which can only be
+	// skipped with the index
+	s, err := os.OpenFile(filename+".shp", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shx, err := os.Open(filename + ".shx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset, _, err := readIndex(shx, 1)
+	shx.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteAt([]byte{0, 0, 0, 0}, offset+4); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	r, err := Open(filename+".shp", Lenient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, problems, err = read(r)
+	if err != nil || !reflect.DeepEqual(rows, []int{0, 3}) {
+		t.Errorf("reader: read rows %v with error %v, want [0 3]", rows, err)
+	}
+	if len(problems) != 2 || !errors.Is(problems[0], ErrInvalidContentLength) ||
+		problems[0].Offset != offset || problems[1].File != "dbf" {
+		t.Errorf("reader: got problems %v, want invalid content length and DBF record 3", problems)
+	}
+
+	_, _, err = read(openSeq())
+	if !errors.Is(err, ErrInvalidContentLength) {
+		t.Errorf("sequential: got error %v, want ErrInvalidContentLength", err)
+	}
+}
@@ -0,0 +1,123 @@
+package shp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrInvalidCount is the error of a CountError for a count or part index
+	// that is negative or does not fit into the record.
+	ErrInvalidCount = errors.New("invalid count")
+	// ErrLimitExceeded is the error of a CountError for a count that exceeds
+	// a limit set with MaxPoints or MaxParts.
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// CountError is returned by Next if the number of parts or points of a shape,
+// or one of its part indexes, is invalid or exceeds a configured limit. It is
+// detected before any memory is allocated for the shape.
+type CountError struct {
+	// What is "parts", "points" or "part index".
+	What  string
+	Count int64
+	// Limit is the configured limit, the maximum that fits into the record
+	// or, for part indexes, the number of points.
+	Limit int64
+	// Err is ErrInvalidCount or ErrLimitExceeded.
+	Err error
+}
+
+func (e *CountError) Error() string {
+	if e.Err == ErrLimitExceeded {
+		return fmt.Sprintf("%d %s exceed limit of %d", e.Count, e.What, e.Limit)
+	}
+	return fmt.Sprintf("invalid %s %d (maximum %d)", e.What, e.Count, e.Limit)
+}
+
+// Unwrap returns ErrInvalidCount or ErrLimitExceeded.
+func (e *CountError) Unwrap() error {
+	return e.Err
+}
+
+// MaxPoints limits the number of points per shape. Shapes with more points
+// cause a CountError. By default, the number of points is only limited by
+// the length of the record.
+func MaxPoints(n int) ReadOption {
+	return func(o *readOptions) {
+		o.maxPoints = n
+	}
+}
+
+// MaxParts limits the number of parts per shape. Shapes with more parts
+// cause a CountError. By default, the number of parts is only limited by the
+// length of the record.
+func MaxParts(n int) ReadOption {
+	return func(o *readOptions) {
+		o.maxParts = n
+	}
+}
+
+// checkCounts validates the number of parts and points that a shape has read
+// from file before the memory for them is allocated. partSize and pointSize
+// are the minimum number of bytes that each part and point needs in the
+// record. If file is an errReader that knows the length of the record and
+// the read options, the counts are checked against them. If a count is
+// invalid, the error is stored in the errReader and false is returned.
+func checkCounts(file io.Reader, parts, points int32, partSize, pointSize int64) bool {
+	er, ok := file.(*errReader)
+	if !ok {
+		er = &errReader{}
+	}
+	if er.e != nil {
+		return false
+	}
+	err := countError("parts", int64(parts), er.opts.maxParts)
+	if err == nil {
+		err = countError("points", int64(points), er.opts.maxPoints)
+	}
+	if err == nil && er.limit > 0 {
+		remaining := er.limit - er.n
+		if need := int64(parts)*partSize + int64(points)*pointSize; need > remaining {
+			if int64(parts)*partSize > remaining {
+				err = &CountError{"parts", int64(parts), remaining / partSize, ErrInvalidCount}
+			} else {
+				err = &CountError{"points", int64(points), (remaining - int64(parts)*partSize) / pointSize, ErrInvalidCount}
+			}
+		}
+	}
+	if err != nil {
+		er.e = err
+		return false
+	}
+	return true
+}
+
+// countError returns an error if count is negative or exceeds max, which is
+// ignored if it is 0.
+func countError(what string, count int64, max int) error {
+	if count < 0 {
+		return &CountError{what, count, 0, ErrInvalidCount}
+	}
+	if max > 0 && count > int64(max) {
+		return &CountError{what, count, int64(max), ErrLimitExceeded}
+	}
+	return nil
+}
+
+// checkParts validates the part indexes that a shape has read from file:
+// they must start at 0, increase and be less than the number of points. If
+// an index is invalid, the error is stored in file if it is an errReader and
+// false is returned.
+func checkParts(file io.Reader, parts []int32, points int32) bool {
+	for i, p := range parts {
+		if p < 0 || p >= points && points > 0 || i == 0 && p != 0 || i > 0 && p < parts[i-1] {
+			if er, ok := file.(*errReader); ok && er.e == nil {
+				er.e = &CountError{"part index", int64(p), int64(points), ErrInvalidCount}
+			}
+			return false
+		}
+	}
+	return true
+}
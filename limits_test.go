@@ -0,0 +1,73 @@
+package shp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// polyLineRecord returns a POLYLINE record with the given counts, part
+// indexes and points. The content length in the record header is calculated
+// from the actual content.
+func polyLineRecord(numParts, numPoints int32, parts []int32, points []Point) []byte {
+	var content bytes.Buffer
+	binary.Write(&content, binary.LittleEndian, POLYLINE)
+	binary.Write(&content, binary.LittleEndian, Box{})
+	binary.Write(&content, binary.LittleEndian, []int32{numParts, numPoints})
+	binary.Write(&content, binary.LittleEndian, parts)
+	binary.Write(&content, binary.LittleEndian, points)
+	var record bytes.Buffer
+	binary.Write(&record, binary.BigEndian, []int32{1, int32(content.Len() / 2)})
+	record.Write(content.Bytes())
+	return record.Bytes()
+}
+
+func TestReadInvalidCounts(t *testing.T) {
+	points := []Point{{0, 0}, {1, 1}, {2, 2}}
+	tests := []struct {
+		name   string
+		record []byte
+		opts   []ReadOption
+		err    error
+	}{
+		{"valid", polyLineRecord(1, 3, []int32{0}, points), nil, nil},
+		{"huge points", polyLineRecord(1, 1<<30, []int32{0}, points), nil, ErrInvalidCount},
+		{"huge parts", polyLineRecord(1<<30, 3, []int32{0}, points), nil, ErrInvalidCount},
+		{"negative points", polyLineRecord(1, -1, []int32{0}, points), nil, ErrInvalidCount},
+		{"negative parts", polyLineRecord(-5, 3, []int32{0}, points), nil, ErrInvalidCount},
+		{"part out of range", polyLineRecord(2, 3, []int32{0, 7}, points), nil, ErrInvalidCount},
+		{"first part not 0", polyLineRecord(1, 3, []int32{1}, points), nil, ErrInvalidCount},
+		{"decreasing parts", polyLineRecord(3, 3, []int32{0, 2, 1}, points), nil, ErrInvalidCount},
+		{"max points", polyLineRecord(1, 3, []int32{0}, points), []ReadOption{MaxPoints(2)}, ErrLimitExceeded},
+		{"max parts", polyLineRecord(2, 3, []int32{0, 1}, points), []ReadOption{MaxParts(1)}, ErrLimitExceeded},
+	}
+
+	for _, test := range tests {
+		o := newReadOptions(test.opts)
+		readers := map[string]interface {
+			Next() bool
+			Err() error
+		}{
+			"reader":    &Reader{shp: newReadSeekCloser(test.record), filelength: int64(len(test.record)), readOptions: o},
+			"seqReader": &seqReader{shp: newReadSeekCloser(test.record), filelength: int64(len(test.record)), readOptions: o},
+		}
+		for name, r := range readers {
+			ok := r.Next()
+			if test.err == nil {
+				if !ok || r.Err() != nil {
+					t.Errorf("%s, %s: got error %v", test.name, name, r.Err())
+				}
+				continue
+			}
+			if ok {
+				t.Errorf("%s, %s: read invalid shape without error", test.name, name)
+				continue
+			}
+			var ce *CountError
+			if !errors.As(r.Err(), &ce) || !errors.Is(r.Err(), test.err) {
+				t.Errorf("%s, %s: got error %v, want %v", test.name, name, r.Err(), test.err)
+			}
+		}
+	}
+}
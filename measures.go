@@ -0,0 +1,51 @@
+package shp
+
+import "io"
+
+// NoData is the measure that is used for points without measure. The
+// specification treats all measures below -10^38 as "no data".
+const NoData = -1e39
+
+// IsNoData reports whether the measure m signals "no data", which is the
+// case for values below -10^38 and NaN.
+func IsNoData(m float64) bool {
+	return m < -1e38 || m != m
+}
+
+// hasM reports whether there is a measure in ms that does not signal "no
+// data".
+func hasM(ms []float64) bool {
+	for _, m := range ms {
+		if !IsNoData(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// noMeasures returns the measures of n points that have none, which are
+// NoData.
+func noMeasures(n int32) []float64 {
+	ms := make([]float64, n)
+	for i := range ms {
+		ms[i] = NoData
+	}
+	return ms
+}
+
+// hasMeasures reports whether the record that is read from file contains
+// the optional M range and measures of n points.
+func hasMeasures(file io.Reader, n int32) bool {
+	return fits(file, 16+8*int64(n))
+}
+
+// fits reports whether size more bytes can be read from the current record
+// of file. If file is not an errReader that knows the length of the record,
+// it is assumed that the bytes are there.
+func fits(file io.Reader, size int64) bool {
+	er, ok := file.(*errReader)
+	if !ok || er.limit <= 0 {
+		return true
+	}
+	return er.e == nil && er.limit-er.n >= size
+}
@@ -0,0 +1,122 @@
+package shp
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIsNoData(t *testing.T) {
+	for _, m := range []float64{NoData, -1e38 * 2, math.Inf(-1), math.NaN()} {
+		if !IsNoData(m) {
+			t.Errorf("IsNoData(%v) = false", m)
+		}
+	}
+	for _, m := range []float64{0, -1e38, 1e39} {
+		if IsNoData(m) {
+			t.Errorf("IsNoData(%v) = true", m)
+		}
+	}
+}
+
+func TestWriteWithoutMeasures(t *testing.T) {
+	filename := filenamePrefix + "without_measures"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POLYLINEZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shapes := []Shape{
+		NewPolyLineZ([][]PointZ{{{0, 0, 1, NoData}, {1, 1, 2, NoData}}}),
+		NewPolyLineZ([][]PointZ{{{0, 0, 1, 5}, {1, 1, 2, NoData}}}),
+	}
+	for _, shape := range shapes {
+		if _, err := w.WriteShape(shape); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	// the first record lacks the M range and the two measures
+	lengths := []int64{112, 144}
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i := range shapes {
+		if !r.Next() {
+			t.Fatalf("could not read shape %d: %v", i, r.Err())
+		}
+		_, s := r.Shape()
+		p := s.(*PolyLineZ)
+		if want := i == 1; p.HasM() != want {
+			t.Errorf("shape %d: HasM() = %v, want %v", i, p.HasM(), want)
+		}
+		if i == 0 && (!reflect.DeepEqual(p.MArray, []float64{NoData, NoData}) || p.MRange != [2]float64{}) {
+			t.Errorf("shape 0: got M range %v and measures %v", p.MRange, p.MArray)
+		}
+		if i == 1 && !reflect.DeepEqual(p.MArray, []float64{5, NoData}) {
+			t.Errorf("shape 1: got measures %v", p.MArray)
+		}
+		if !reflect.DeepEqual(p.ZArray, []float64{1, 2}) {
+			t.Errorf("shape %d: got Z values %v", i, p.ZArray)
+		}
+	}
+
+	shx, err := os.Open(filename + ".shx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shx.Close()
+	for i, want := range lengths {
+		_, length, err := readIndex(shx, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if length != want {
+			t.Errorf("record %d has content length %d, want %d", i, length, want)
+		}
+	}
+}
+
+func TestPointZWithoutMeasure(t *testing.T) {
+	filename := filenamePrefix + "pointz_without_measure"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POINTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := []PointZ{{1, 2, 3, NoData}, {4, 5, 6, 7}}
+	for i := range points {
+		if _, err := w.WriteShape(&points[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	shp, err := os.Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shp.Close()
+	sr := SequentialReaderFromExt(shp, nil)
+	defer sr.Close()
+	for i, want := range points {
+		if !sr.Next() {
+			t.Fatalf("could not read point %d: %v", i, sr.Err())
+		}
+		_, s := sr.Shape()
+		p := s.(*PointZ)
+		if p.X != want.X || p.Y != want.Y || p.Z != want.Z || p.HasM() != want.HasM() {
+			t.Errorf("read %v, want %v", *p, want)
+		}
+		if p.HasM() && p.M != want.M {
+			t.Errorf("read measure %v, want %v", p.M, want.M)
+		}
+	}
+	if sr.Next() || sr.Err() != nil {
+		t.Errorf("unexpected end of file: %v", sr.Err())
+	}
+}
@@ -0,0 +1,177 @@
+package shp
+
+// Normalize sets NumParts, NumPoints and the Box from the slices of the
+// PolyLine. A PolyLine with points but without parts gets a single part.
+func (p *PolyLine) Normalize() {
+	p.Parts = normalizeParts(p.Parts, len(p.Points))
+	p.NumParts, p.NumPoints = int32(len(p.Parts)), int32(len(p.Points))
+	p.Box = BBoxFromPoints(p.Points)
+}
+
+// Normalize sets NumParts, NumPoints and the Box from the slices of the
+// Polygon. A Polygon with points but without parts gets a single part.
+func (p *Polygon) Normalize() {
+	(*PolyLine)(p).Normalize()
+}
+
+// Normalize sets NumPoints and the Box from the points of the MultiPoint.
+func (p *MultiPoint) Normalize() {
+	p.NumPoints = int32(len(p.Points))
+	p.Box = BBoxFromPoints(p.Points)
+}
+
+// Normalize sets NumParts, NumPoints, the Box, ZRange and MRange from the
+// slices of the PolyLineZ. ZArray and MArray are cut or padded to the number
+// of points, missing Z values are 0 and missing measures are NoData.
+func (p *PolyLineZ) Normalize() {
+	p.Parts = normalizeParts(p.Parts, len(p.Points))
+	p.NumParts, p.NumPoints = int32(len(p.Parts)), int32(len(p.Points))
+	p.Box = BBoxFromPoints(p.Points)
+	p.ZArray = resize(p.ZArray, len(p.Points), 0)
+	p.ZRange = valueRange(p.ZArray)
+	p.MArray = resize(p.MArray, len(p.Points), NoData)
+	p.MRange = valueRange(p.MArray)
+}
+
+// Normalize is like PolyLineZ.Normalize for the PolygonZ.
+func (p *PolygonZ) Normalize() {
+	(*PolyLineZ)(p).Normalize()
+}
+
+// Normalize sets NumPoints, the Box, ZRange and MRange from the slices of the
+// MultiPointZ. ZArray and MArray are cut or padded like for a PolyLineZ.
+func (p *MultiPointZ) Normalize() {
+	p.NumPoints = int32(len(p.Points))
+	p.Box = BBoxFromPoints(p.Points)
+	p.ZArray = resize(p.ZArray, len(p.Points), 0)
+	p.ZRange = valueRange(p.ZArray)
+	p.MArray = resize(p.MArray, len(p.Points), NoData)
+	p.MRange = valueRange(p.MArray)
+}
+
+// Normalize sets NumParts, NumPoints, the Box and MRange from the slices of
+// the PolyLineM. MArray is cut or padded to the number of points, missing
+// measures are NoData.
+func (p *PolyLineM) Normalize() {
+	p.Parts = normalizeParts(p.Parts, len(p.Points))
+	p.NumParts, p.NumPoints = int32(len(p.Parts)), int32(len(p.Points))
+	p.Box = BBoxFromPoints(p.Points)
+	p.MArray = resize(p.MArray, len(p.Points), NoData)
+	p.MRange = valueRange(p.MArray)
+}
+
+// Normalize is like PolyLineM.Normalize for the PolygonM.
+func (p *PolygonM) Normalize() {
+	(*PolyLineM)(p).Normalize()
+}
+
+// Normalize sets NumPoints, the Box and MRange from the slices of the
+// MultiPointM. MArray is cut or padded like for a PolyLineM.
+func (p *MultiPointM) Normalize() {
+	p.NumPoints = int32(len(p.Points))
+	p.Box = BBoxFromPoints(p.Points)
+	p.MArray = resize(p.MArray, len(p.Points), NoData)
+	p.MRange = valueRange(p.MArray)
+}
+
+// Normalize sets NumParts, NumPoints, the Box, ZRange and MRange from the
+// slices of the MultiPatch. PartTypes, ZArray and MArray are cut or padded
+// like for a PolyLineZ; missing part types are 0 (triangle strip).
+func (p *MultiPatch) Normalize() {
+	p.Parts = normalizeParts(p.Parts, len(p.Points))
+	p.NumParts, p.NumPoints = int32(len(p.Parts)), int32(len(p.Points))
+	if len(p.PartTypes) != len(p.Parts) {
+		types := make([]int32, len(p.Parts))
+		copy(types, p.PartTypes)
+		p.PartTypes = types
+	}
+	p.Box = BBoxFromPoints(p.Points)
+	p.ZArray = resize(p.ZArray, len(p.Points), 0)
+	p.ZRange = valueRange(p.ZArray)
+	p.MArray = resize(p.MArray, len(p.Points), NoData)
+	p.MRange = valueRange(p.MArray)
+}
+
+// normalized returns a normalized copy of shape, which itself is left
+// unchanged. Shapes without counts or ranges are returned as they are.
+func normalized(shape Shape) Shape {
+	switch s := shape.(type) {
+	case *PolyLine:
+		c := *s
+		c.Normalize()
+		return &c
+	case *Polygon:
+		c := *s
+		c.Normalize()
+		return &c
+	case *MultiPoint:
+		c := *s
+		c.Normalize()
+		return &c
+	case *PolyLineZ:
+		c := *s
+		c.Normalize()
+		return &c
+	case *PolygonZ:
+		c := *s
+		c.Normalize()
+		return &c
+	case *MultiPointZ:
+		c := *s
+		c.Normalize()
+		return &c
+	case *PolyLineM:
+		c := *s
+		c.Normalize()
+		return &c
+	case *PolygonM:
+		c := *s
+		c.Normalize()
+		return &c
+	case *MultiPointM:
+		c := *s
+		c.Normalize()
+		return &c
+	case *MultiPatch:
+		c := *s
+		c.Normalize()
+		return &c
+	}
+	return shape
+}
+
+// normalizeParts returns a single part starting at 0 if there are points but
+// no parts, otherwise parts.
+func normalizeParts(parts []int32, numPoints int) []int32 {
+	if len(parts) == 0 && numPoints > 0 {
+		return []int32{0}
+	}
+	return parts
+}
+
+// resize returns a with length n. Missing values are set to fill. If a has
+// the wrong length, a new slice is returned so that a remains unchanged.
+func resize(a []float64, n int, fill float64) []float64 {
+	if len(a) == n {
+		return a
+	}
+	b := make([]float64, n)
+	copy(b, a)
+	for i := len(a); i < n; i++ {
+		b[i] = fill
+	}
+	return b
+}
+
+// valueRange returns the minimum and maximum of the values in a, ignoring
+// values that signal "no data" and NaN. It is zero if there are none.
+func valueRange(a []float64) [2]float64 {
+	var r [2]float64
+	var ok bool
+	for _, v := range a {
+		if !IsNoData(v) {
+			extendRange(&r, &ok, v)
+		}
+	}
+	return r
+}
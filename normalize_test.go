@@ -0,0 +1,79 @@
+package shp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	p := &PolygonZ{
+		NumParts: 7, // wrong on purpose
+		Points:   []Point{{0, 0}, {2, 0}, {2, 3}, {0, 0}},
+		ZArray:   []float64{5, -2},
+		MArray:   []float64{1, NoData, 4, 3},
+	}
+	p.Normalize()
+	if p.NumParts != 1 || !reflect.DeepEqual(p.Parts, []int32{0}) || p.NumPoints != 4 {
+		t.Errorf("got %d parts %v and %d points", p.NumParts, p.Parts, p.NumPoints)
+	}
+	if want := (Box{0, 0, 2, 3}); p.Box != want {
+		t.Errorf("got box %v, want %v", p.Box, want)
+	}
+	if want := []float64{5, -2, 0, 0}; !reflect.DeepEqual(p.ZArray, want) {
+		t.Errorf("got Z values %v, want %v", p.ZArray, want)
+	}
+	if p.ZRange != [2]float64{-2, 5} || p.MRange != [2]float64{1, 4} {
+		t.Errorf("got Z range %v and M range %v", p.ZRange, p.MRange)
+	}
+
+	m := &MultiPatch{
+		Parts:     []int32{0, 2},
+		PartTypes: []int32{5},
+		Points:    []Point{{0, 0}, {1, 1}, {2, 2}},
+	}
+	m.Normalize()
+	if !reflect.DeepEqual(m.PartTypes, []int32{5, 0}) || len(m.MArray) != 3 || m.MArray[0] != NoData {
+		t.Errorf("got part types %v and measures %v", m.PartTypes, m.MArray)
+	}
+}
+
+func TestWriteNormalized(t *testing.T) {
+	filename := filenamePrefix + "normalized"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", MULTIPOINTM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape := &MultiPointM{
+		Points: []Point{{1, 2}, {3, -4}},
+		MArray: []float64{10},
+	}
+	if _, err := w.WriteShape(shape); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if shape.NumPoints != 0 || len(shape.MArray) != 1 {
+		t.Errorf("writing changed the shape to %+v", shape)
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !r.Next() {
+		t.Fatalf("could not read shape: %v", r.Err())
+	}
+	_, s := r.Shape()
+	got := s.(*MultiPointM)
+	want := &MultiPointM{
+		Box:       Box{1, -4, 3, 2},
+		NumPoints: 2,
+		Points:    shape.Points,
+		MRange:    [2]float64{10, 10},
+		MArray:    []float64{10, NoData},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package shp
+
+import "io"
+
+// ReadOption configures how a Reader or SequentialReader reads a shapefile.
+// Options are passed to the functions that open a shapefile, e.g. Open or
+// SequentialReaderFromExt.
+type ReadOption func(*readOptions)
+
+// readOptions holds the settings that are shared by all readers.
+type readOptions struct {
+	skipDeleted bool
+	lenient     bool
+	enforceType bool
+	maxPoints   int
+	maxParts    int
+
+	// memo is the memo file that is passed with MemoFile.
+	memo       io.ReadCloser
+	memoFoxPro bool
+}
+
+// newReadOptions returns the settings that result from applying opts.
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// EnforceShapeType makes Next report records whose shape type is neither
+// NULL nor the type in the header of the shapefile as FormatError with
+// ErrShapeTypeMismatch. In lenient mode, such records are skipped. By
+// default, each record is read according to its own shape type.
+func EnforceShapeType() ReadOption {
+	return func(o *readOptions) {
+		o.enforceType = true
+	}
+}
+
+// SkipDeleted makes Next skip all features whose record in the DBF table is
+// marked as deleted.
+func SkipDeleted() ReadOption {
+	return func(o *readOptions) {
+		o.skipDeleted = true
+	}
+}
+
+// MemoFile sets the memo file m of the DBF table that is passed to
+// SequentialReaderFromExt, so that memo fields return their contents instead
+// of the numbers of their memo blocks. foxPro selects the format of m: true
+// for a FoxPro .fpt file, false for a dBase .dbt file. If m cannot seek, it is
+// copied into memory or a temporary file first. m is closed by Close of the
+// SequentialReader. The option is only used by SequentialReaderFromExt; the
+// other readers look up the memo file next to the DBF file.
+func MemoFile(m io.ReadCloser, foxPro bool) ReadOption {
+	return func(o *readOptions) {
+		o.memo = m
+		o.memoFoxPro = foxPro
+	}
+}
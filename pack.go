@@ -0,0 +1,199 @@
+package shp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
+	"github.com/jonas-p/go-shp/internal/tempfile"
+)
+
+// Pack physically removes the features whose records in the DBF table are
+// marked as deleted from the shapefile at filename. The records are removed
+// from the SHP, SHX and DBF files, the remaining shapes are renumbered and
+// written in the order of the index, and the bounding box is recalculated.
+// All three files are written to temporary files first, which replace the
+// original files only after all of them have been written.
+func Pack(filename string) error {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	deleted, err := deletedRows(base + ".dbf")
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, d := range deleted {
+		if d {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+
+	shp, shx, err := packShapes(base, deleted)
+	if err != nil {
+		return err
+	}
+	table, err := packTable(base + ".dbf")
+	if err != nil {
+		os.Remove(shp.Name())
+		os.Remove(shx.Name())
+		return err
+	}
+	tmps := []*tempfile.File{shp, shx, table}
+	for i, tmp := range tmps {
+		if err := tmp.Replace(); err != nil {
+			for _, tmp := range tmps[i+1:] {
+				os.Remove(tmp.Name())
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// packTable writes the records of the DBF file at filename that are not
+// deleted to a temporary file, which is closed.
+func packTable(filename string) (*tempfile.File, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tmp, err := tempfile.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = dbf.PackTo(tmp, f)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// deletedRows returns the deletion flags of all records of the DBF file at
+// filename.
+func deletedRows(filename string) ([]bool, error) {
+	d, err := dbf.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	deleted := make([]bool, d.NumRecords())
+	for d.Next() {
+		deleted[d.Row()] = d.Deleted()
+	}
+	return deleted, d.Err()
+}
+
+// packShapes writes the shapes of the shapefile base.shp that are not
+// deleted, together with their index, to temporary files, which are closed.
+func packShapes(base string, deleted []bool) (*tempfile.File, *tempfile.File, error) {
+	r, err := os.Open(base + ".shp")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	idx, err := os.Open(base + ".shx")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer idx.Close()
+
+	shp, err := tempfile.Create(base + ".shp")
+	if err != nil {
+		return nil, nil, err
+	}
+	shx, err := tempfile.Create(base + ".shx")
+	if err != nil {
+		shp.Discard()
+		return nil, nil, err
+	}
+	w := &Writer{shp: shp, shx: shx}
+	err = w.pack(r, idx, deleted)
+	if cerr := shp.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := shx.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(shp.Name())
+		os.Remove(shx.Name())
+		return nil, nil, err
+	}
+	return shp, shx, nil
+}
+
+// pack copies the records of the shapes that are not deleted from the SHP
+// file read from r, using the index read from idx, to the files of w and
+// writes their headers.
+func (w *Writer) pack(r, idx io.ReadSeeker, deleted []bool) error {
+	er := &errReader{Reader: r}
+	r.Seek(32, io.SeekStart)
+	binary.Read(er, binary.LittleEndian, &w.GeometryType)
+	if er.e != nil {
+		return &FormatError{File: "shp", Offset: 0, Err: truncated(er.e)}
+	}
+	size, err := idx.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	w.shp.Seek(100, io.SeekStart)
+	w.shx.Seek(100, io.SeekStart)
+
+	for row := 0; row < int(size-100)/8; row++ {
+		content, err := readRecord(r, idx, row)
+		if err != nil {
+			return err
+		}
+		if row < len(deleted) && deleted[row] {
+			continue
+		}
+		shape, err := recordShape(content)
+		if err != nil {
+			return fmt.Errorf("Error when reading shape %d: %w", row, err)
+		}
+		w.extent.add(shape)
+
+		start, _ := w.shp.Seek(0, io.SeekCurrent)
+		w.num++
+		length := int32(len(content) / 2)
+		binary.Write(w.shp, binary.BigEndian, []int32{w.num, length})
+		if _, err := w.shp.Write(content); err != nil {
+			return err
+		}
+		binary.Write(w.shx, binary.BigEndian, []int32{int32(start / 2), length})
+	}
+	w.writeHeader(w.shx)
+	w.writeHeader(w.shp)
+	return nil
+}
+
+// recordShape decodes the shape whose record content is given.
+func recordShape(content []byte) (Shape, error) {
+	er := &errReader{Reader: bytes.NewReader(content), limit: int64(len(content))}
+	var shapetype ShapeType
+	binary.Read(er, binary.LittleEndian, &shapetype)
+	if er.e != nil {
+		return nil, er.e
+	}
+	shape, err := newShape(shapetype)
+	if err != nil {
+		return nil, err
+	}
+	shape.read(er)
+	if er.e != nil && er.e != io.EOF {
+		return nil, er.e
+	}
+	return shape, nil
+}
@@ -0,0 +1,211 @@
+package shp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/jonas-p/go-shp/dbf"
+)
+
+// createDeletedTest writes a shapefile with four points and marks the
+// records of the first and third point as deleted.
+func createDeletedTest(filename string, t *testing.T) {
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		n := int(w.Write(&Point{float64(i), float64(10 * i)}))
+		w.WriteAttribute(n, 0, fmt.Sprintf("point %d", i))
+	}
+	w.Close()
+
+	d, err := dbf.Append(filename + ".dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Delete(0)
+	d.Delete(2)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkipDeleted(t *testing.T) {
+	filename := filenamePrefix + "deleted"
+	defer removeShapefile(filename)
+	createDeletedTest(filename, t)
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row, want := range []bool{true, false, true, false} {
+		if got, err := r.IsDeleted(row); err != nil || got != want {
+			t.Errorf("row %d: got deleted %v with error %v, want %v", row, got, err, want)
+		}
+	}
+	n := 0
+	for r.Next() {
+		if i, _ := r.Shape(); IsDeleted(r) != (i%2 == 0) {
+			t.Errorf("shape %d: got deleted %v", i, IsDeleted(r))
+		}
+		n++
+	}
+	r.Close()
+	if n != 4 {
+		t.Errorf("got %d shapes without SkipDeleted, want 4", n)
+	}
+
+	r, err = Open(filename+".shp", SkipDeleted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for r.Next() {
+		names = append(names, r.Attribute(0))
+	}
+	r.Close()
+	if fmt.Sprint(names) != "[point 1 point 3]" {
+		t.Errorf("got %q with Reader, want point 1 and point 3", names)
+	}
+
+	shp := openFile(filename+".shp", t)
+	dbf := openFile(filename+".dbf", t)
+	sr := SequentialReaderFromExt(shp, dbf, SkipDeleted())
+	names = nil
+	for sr.Next() {
+		if IsDeleted(sr) {
+			t.Error("SequentialReader returned deleted record")
+		}
+		names = append(names, sr.Attribute(0))
+	}
+	if err := sr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sr.Close()
+	if fmt.Sprint(names) != "[point 1 point 3]" {
+		t.Errorf("got %q with SequentialReader, want point 1 and point 3", names)
+	}
+}
+
+func TestIsDeletedError(t *testing.T) {
+	filename := filenamePrefix + "deleted_error"
+	defer removeShapefile(filename)
+	createDeletedTest(filename, t)
+
+	// corrupt the deletion indicator of the second record
+	d, err := os.OpenFile(filename+".dbf", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.WriteAt([]byte{'?'}, 32+32+1+11); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	r, err := Open(filename+".shp", SkipDeleted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.IsDeleted(1); !errors.Is(err, dbf.ErrDeletionIndicator) {
+		t.Errorf("got error %v, want ErrDeletionIndicator", err)
+	}
+	for r.Next() {
+	}
+	if !errors.Is(r.Err(), dbf.ErrDeletionIndicator) {
+		t.Errorf("Next ended with error %v, want ErrDeletionIndicator", r.Err())
+	}
+}
+
+func TestPack(t *testing.T) {
+	filename := filenamePrefix + "pack"
+	defer removeShapefile(filename)
+	createDeletedTest(filename, t)
+	modes := map[string]os.FileMode{}
+	for _, ext := range []string{".shp", ".shx", ".dbf"} {
+		if err := os.Chmod(filename+ext, 0640); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(filename + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modes[ext] = fi.Mode()
+	}
+
+	if err := Pack(filename + ".shp"); err != nil {
+		t.Fatal(err)
+	}
+	for ext, want := range modes {
+		fi, err := os.Stat(filename + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode() != want {
+			t.Errorf("%s: got mode %v, want %v", ext, fi.Mode(), want)
+		}
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if want := (Box{1, 10, 3, 30}); r.BBox() != want {
+		t.Errorf("got bounding box %v, want %v", r.BBox(), want)
+	}
+	if got := r.AttributeCount(); got != 2 {
+		t.Errorf("got %d attribute rows, want 2", got)
+	}
+	want := []Point{{1, 10}, {3, 30}}
+	n := 0
+	for r.Next() {
+		i, shape := r.Shape()
+		if i != n {
+			t.Errorf("got shape number %d, want %d", i, n)
+		}
+		if p := shape.(*Point); *p != want[n] {
+			t.Errorf("shape %d: got %v, want %v", i, *p, want[n])
+		}
+		if got, want := r.Attribute(0), fmt.Sprintf("point %d", 2*n+1); got != want {
+			t.Errorf("shape %d: got attribute %q, want %q", i, got, want)
+		}
+		if IsDeleted(r) {
+			t.Errorf("shape %d is still deleted", i)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("got %d shapes, want 2", n)
+	}
+
+	fi, err := os.Stat(filename + ".shx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 100+2*8 {
+		t.Errorf("got SHX size %d, want %d", fi.Size(), 100+2*8)
+	}
+}
+
+func TestPackTruncated(t *testing.T) {
+	filename := filenamePrefix + "pack_truncated"
+	defer removeShapefile(filename)
+	createDeletedTest(filename, t)
+	if err := os.Truncate(filename+".shp", 34); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Pack(filename + ".shp")
+	var fe *FormatError
+	if !errors.Is(err, ErrTruncated) || !errors.As(err, &fe) || fe.File != "shp" {
+		t.Errorf("got error %v, want truncated SHP file", err)
+	}
+}
@@ -2,12 +2,15 @@ package shp
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
 )
 
 // Reader provides a interface for reading Shapefiles. Calls
@@ -17,7 +20,10 @@ import (
 type Reader struct {
 	GeometryType ShapeType
 	bbox         Box
+	zRange       [2]float64
+	mRange       [2]float64
 	err          error
+	readOptions
 
 	shp        readSeekCloser
 	shape      Shape
@@ -25,11 +31,19 @@ type Reader struct {
 	filename   string
 	filelength int64
 
-	dbf             readSeekCloser
-	dbfFields       []Field
-	dbfNumRecords   int32
-	dbfHeaderLength int16
-	dbfRecordLength int16
+	// opener opens the file with the given extension that belongs to the
+	// shapefile. If nil, the file is opened from disk using filename.
+	opener func(ext string) (readSeekCloser, error)
+	// archive is closed together with the Reader if it is non-nil.
+	archive io.Closer
+
+	dbf *dbf.Reader
+
+	// problems holds the records that were skipped in lenient mode.
+	problems []*FormatError
+	// offsets holds the sorted offsets of all records from the SHX file. It
+	// is loaded when the first bad record is skipped in lenient mode.
+	offsets []int64
 }
 
 type readSeekCloser interface {
@@ -39,7 +53,7 @@ type readSeekCloser interface {
 }
 
 // Open opens a Shapefile for reading.
-func Open(filename string) (*Reader, error) {
+func Open(filename string, opts ...ReadOption) (*Reader, error) {
 	ext := filepath.Ext(filename)
 	if strings.ToLower(ext) != ".shp" {
 		return nil, fmt.Errorf("Invalid file extension: %s", filename)
@@ -48,7 +62,11 @@ func Open(filename string) (*Reader, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &Reader{filename: strings.TrimSuffix(filename, ext), shp: shp}
+	s := &Reader{
+		filename:    strings.TrimSuffix(filename, ext),
+		shp:         shp,
+		readOptions: newReadOptions(opts),
+	}
 	return s, s.readHeaders()
 }
 
@@ -57,8 +75,20 @@ func (r *Reader) BBox() Box {
 	return r.bbox
 }
 
+// ZRange returns the minimum and maximum Z value of the shapefile from its
+// header. It is zero for shape types without Z values.
+func (r *Reader) ZRange() [2]float64 {
+	return r.zRange
+}
+
+// MRange returns the minimum and maximum measure of the shapefile from its
+// header. It is zero for shape types without measures.
+func (r *Reader) MRange() [2]float64 {
+	return r.mRange
+}
+
 // Read and parse headers in the Shapefile. This will
-// fill out GeometryType, filelength and bbox.
+// fill out GeometryType, filelength, bbox and the Z and M ranges.
 func (r *Reader) readHeaders() error {
 	er := &errReader{Reader: r.shp}
 	// don't trust the the filelength in the header
@@ -74,8 +104,13 @@ func (r *Reader) readHeaders() error {
 	r.bbox.MinY = readFloat64(er)
 	r.bbox.MaxX = readFloat64(er)
 	r.bbox.MaxY = readFloat64(er)
+	binary.Read(er, binary.LittleEndian, &r.zRange)
+	binary.Read(er, binary.LittleEndian, &r.mRange)
 	r.shp.Seek(100, 0)
-	return er.e
+	if er.e != nil {
+		return &FormatError{File: "shp", Offset: 0, Err: truncated(er.e)}
+	}
+	return nil
 }
 
 func readFloat64(r io.Reader) float64 {
@@ -91,10 +126,25 @@ func (r *Reader) Close() error {
 		if r.dbf != nil {
 			r.dbf.Close()
 		}
+		if r.archive != nil {
+			r.archive.Close()
+		}
 	}
 	return r.err
 }
 
+// open opens the file with extension ext that belongs to the shapefile.
+func (r *Reader) open(ext string) (readSeekCloser, error) {
+	if r.opener != nil {
+		return r.opener(ext)
+	}
+	f, err := os.Open(r.filename + ext)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
 // Shape returns the most recent feature that was read by
 // a call to Next. It returns two values, the int is the
 // object index starting from zero in the shapefile which
@@ -141,83 +191,164 @@ func newShape(shapetype ShapeType) (Shape, error) {
 	case MULTIPATCH:
 		return new(MultiPatch), nil
 	default:
-		return nil, fmt.Errorf("Unsupported shape type: %v", shapetype)
+		return nil, fmt.Errorf("%w %v", ErrUnsupportedShapeType, shapetype)
 	}
 }
 
 // Next reads in the next Shape in the Shapefile, which
 // will then be available through the Shape method. It
 // returns false when the reader has reached the end of the
-// file or encounters an error.
+// file or encounters an error. With the SkipDeleted option,
+// features whose records are marked as deleted are skipped.
 func (r *Reader) Next() bool {
-	cur, _ := r.shp.Seek(0, io.SeekCurrent)
-	if cur >= r.filelength {
-		return false
+	for r.next() {
+		if !r.skipDeleted {
+			return true
+		}
+		deleted, err := r.IsDeleted(int(r.num) - 1)
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if !deleted {
+			return true
+		}
+	}
+	return false
+}
+
+// next reads the next shape regardless of its deletion flag. In lenient
+// mode, bad records are skipped and added to the problems.
+func (r *Reader) next() bool {
+	for {
+		cur, _ := r.shp.Seek(0, io.SeekCurrent)
+		if cur >= r.filelength {
+			return false
+		}
+		bad, err := r.readShape(cur)
+		if err == nil && bad == nil && r.lenient {
+			bad = r.checkRow()
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if bad == nil {
+			return true
+		}
+		r.problems = append(r.problems, bad)
 	}
+}
 
+// readShape reads the record at cur. If the record is bad, it returns the
+// problem as error, or in lenient mode, it moves on to the next record and
+// returns the problem as bad record.
+func (r *Reader) readShape(cur int64) (bad *FormatError, err error) {
 	var size int32
 	var shapetype ShapeType
-	er := &errReader{Reader: r.shp}
+	er := &errReader{Reader: r.shp, opts: r.readOptions}
 	binary.Read(er, binary.BigEndian, &r.num)
 	binary.Read(er, binary.BigEndian, &size)
 	binary.Read(er, binary.LittleEndian, &shapetype)
 	if er.e != nil {
-		if er.e != io.EOF {
-			r.err = fmt.Errorf("Error when reading metadata of next shape: %v", er.e)
-		} else {
-			r.err = io.EOF
+		if er.e == io.EOF && er.n == 0 {
+			return nil, io.EOF
 		}
-		return false
+		return r.skip(cur, -1, truncated(er.e))
+	}
+	if size < 2 {
+		return r.skip(cur, -1, fmt.Errorf("%w %d", ErrInvalidContentLength, size))
+	}
+	// the record must not extend beyond the end of the file
+	length := int64(size)*2 + 8
+	if cur+length > r.filelength {
+		return r.skip(cur, length, ErrTruncated)
 	}
 
-	var err error
 	r.shape, err = newShape(shapetype)
 	if err != nil {
-		r.err = fmt.Errorf("Error decoding shape type: %v", err)
-		return false
+		return r.skip(cur, length, err)
 	}
-	r.shape.read(er)
-	if er.e != nil {
-		r.err = fmt.Errorf("Error while reading next shape: %v", er.e)
-		return false
+	if r.enforceType && shapetype != NULL && shapetype != r.GeometryType {
+		return r.skip(cur, length, mismatch(shapetype, r.GeometryType))
+	}
+	if err := readContent(r.shape, er, length); err != nil {
+		return r.skip(cur, length, err)
 	}
 
 	// move to next object
-	r.shp.Seek(int64(size)*2+cur+8, 0)
-	return true
+	r.shp.Seek(cur+length, io.SeekStart)
+	return nil, nil
 }
 
 // Opens DBF file using r.filename + "dbf". This method
-// will parse the header and fill out all dbf* values int
-// the f object.
-func (r *Reader) openDbf() (err error) {
+// will parse the header and the field descriptors. If the
+// table has memo fields, the memo file (.dbt or .fpt) is
+// opened as well.
+func (r *Reader) openDbf() error {
 	if r.dbf != nil {
-		return
+		return nil
 	}
 
-	r.dbf, err = os.Open(r.filename + ".dbf")
+	f, err := r.open(".dbf")
 	if err != nil {
-		return
+		return err
 	}
+	d, err := dbf.NewReader(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if m, foxPro := memoFile(d, r.open); m != nil {
+		if err := d.SetMemo(m, foxPro); err != nil {
+			m.Close()
+			d.Close()
+			return err
+		}
+	}
+	r.dbf = d
+	return nil
+}
 
-	// read header
-	r.dbf.Seek(4, io.SeekStart)
-	binary.Read(r.dbf, binary.LittleEndian, &r.dbfNumRecords)
-	binary.Read(r.dbf, binary.LittleEndian, &r.dbfHeaderLength)
-	binary.Read(r.dbf, binary.LittleEndian, &r.dbfRecordLength)
-
-	r.dbf.Seek(20, io.SeekCurrent) // skip padding
-	numFields := int(math.Floor(float64(r.dbfHeaderLength-33) / 32.0))
-	r.dbfFields = make([]Field, numFields)
-	binary.Read(r.dbf, binary.LittleEndian, &r.dbfFields)
-	return
+// memoFile opens the memo file of the DBF table d if it has memo fields. The
+// extensions .dbt and .fpt are passed to open in the order that suits the
+// version of the table, see dbf.MemoExtensions. It returns nil if there is
+// no memo file, and whether it is a FoxPro memo file.
+func memoFile(d *dbf.Reader, open func(ext string) (readSeekCloser, error)) (readSeekCloser, bool) {
+	if d == nil {
+		return nil, false
+	}
+	for _, field := range d.Fields() {
+		if field.Fieldtype != 'M' {
+			continue
+		}
+		for _, ext := range dbf.MemoExtensions(d.Header().Version) {
+			if m, err := open(ext); err == nil {
+				return m, ext == ".fpt"
+			}
+		}
+		break
+	}
+	return nil, false
 }
 
 // Fields returns a slice of Fields that are present in the
 // DBF table.
 func (r *Reader) Fields() []Field {
-	r.openDbf() // make sure we have dbf file to read from
-	return r.dbfFields
+	if r.openDbf() != nil { // make sure we have dbf file to read from
+		return nil
+	}
+	return r.dbf.Fields()
+}
+
+// FieldIndex returns the index of the field called name in the DBF table. An
+// exact match is preferred, otherwise the names are compared
+// case-insensitively.
+func (r *Reader) FieldIndex(name string) (int, bool) {
+	if r.openDbf() != nil { // make sure we have dbf file to read from
+		return -1, false
+	}
+	return r.dbf.FieldIndex(name)
 }
 
 // Err returns the last non-EOF error encountered.
@@ -230,20 +361,54 @@ func (r *Reader) Err() error {
 
 // AttributeCount returns number of records in the DBF table.
 func (r *Reader) AttributeCount() int {
-	r.openDbf() // make sure we have a dbf file to read from
-	return int(r.dbfNumRecords)
+	if r.openDbf() != nil { // make sure we have a dbf file to read from
+		return 0
+	}
+	return r.dbf.NumRecords()
 }
 
 // ReadAttribute returns the attribute value at row for field in
 // the DBF table as a string. Both values starts at 0.
 func (r *Reader) ReadAttribute(row int, field int) string {
-	r.openDbf() // make sure we have a dbf file to read from
-	seekTo := 1 + int64(r.dbfHeaderLength) + (int64(row) * int64(r.dbfRecordLength))
-	for n := 0; n < field; n++ {
-		seekTo += int64(r.dbfFields[n].Size)
-	}
-	r.dbf.Seek(seekTo, io.SeekStart)
-	buf := make([]byte, r.dbfFields[field].Size)
-	r.dbf.Read(buf)
-	return strings.Trim(string(buf[:]), " ")
+	if r.openDbf() != nil { // make sure we have a dbf file to read from
+		return ""
+	}
+	return r.dbf.ReadAttribute(row, field)
+}
+
+// IsDeleted reports whether the record at row in the DBF table is marked as
+// deleted. It returns false if there is no DBF table, and an error if the
+// table cannot be read or the record has an invalid deletion indicator.
+func (r *Reader) IsDeleted(row int) (bool, error) {
+	if err := r.openDbf(); err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrFileNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return r.dbf.IsDeleted(row)
+}
+
+// deleted reports whether the record of the most recent feature is marked as
+// deleted. Records that cannot be read are not.
+func (r *Reader) deleted() bool {
+	deleted, _ := r.IsDeleted(int(r.num) - 1)
+	return deleted
+}
+
+func (r *Reader) dbfHeader() (dbf.Header, bool) {
+	if r.openDbf() != nil {
+		return dbf.Header{}, false
+	}
+	return r.dbf.Header(), true
+}
+
+// DBF returns the reader for the DBF table of the shapefile, which provides
+// access to the table header and typed values. It returns an error if the
+// DBF file cannot be opened.
+func (r *Reader) DBF() (*dbf.Reader, error) {
+	if err := r.openDbf(); err != nil {
+		return nil, err
+	}
+	return r.dbf, nil
 }
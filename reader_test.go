@@ -2,6 +2,7 @@ package shp
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"testing"
@@ -525,3 +526,48 @@ func TestReadInvalidShapeType(t *testing.T) {
 		})
 	}
 }
+
+func TestEnforceShapeType(t *testing.T) {
+	point := []byte{0, 0, 0, 1, 0, 0, 0, 10, 1, 0, 0, 0}
+	point = append(point, make([]byte, 16)...)
+	null := []byte{0, 0, 0, 2, 0, 0, 0, 2, 0, 0, 0, 0}
+	line := polyLineRecord(1, 2, []int32{0}, []Point{{0, 0}, {1, 1}})
+	var file []byte
+	for _, record := range [][]byte{point, null, line, point} {
+		file = append(file, record...)
+	}
+
+	for _, opts := range [][]ReadOption{nil, {EnforceShapeType()}, {EnforceShapeType(), Lenient()}} {
+		o := newReadOptions(opts)
+		readers := map[string]SequentialReader{
+			"reader": &Reader{shp: newReadSeekCloser(file), filelength: int64(len(file)),
+				GeometryType: POINT, readOptions: o},
+			"seqReader": &seqReader{shp: newReadSeekCloser(file), filelength: int64(len(file)),
+				geometryType: POINT, readOptions: o},
+		}
+		for name, r := range readers {
+			n := 0
+			for r.Next() {
+				n++
+			}
+			var want int
+			switch {
+			case !o.enforceType:
+				want = 4
+			case o.lenient:
+				want = 3
+			default:
+				want = 2
+			}
+			if n != want {
+				t.Errorf("%s with %+v: read %d shapes, want %d", name, o, n, want)
+			}
+			if mismatch := errors.Is(r.Err(), ErrShapeTypeMismatch); mismatch != (o.enforceType && !o.lenient) {
+				t.Errorf("%s with %+v: got error %v", name, o, r.Err())
+			}
+			if o.lenient && (len(Problems(r)) != 1 || !errors.Is(Problems(r)[0], ErrShapeTypeMismatch)) {
+				t.Errorf("%s with %+v: got problems %v", name, o, Problems(r))
+			}
+		}
+	}
+}
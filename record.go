@@ -0,0 +1,73 @@
+package shp
+
+import (
+	"fmt"
+
+	"github.com/jonas-p/go-shp/dbf"
+)
+
+// Record holds the typed attributes of a feature. The values can be looked
+// up by name or position, converted to a map and written to another
+// shapefile with Writer.WriteRecord.
+type Record = dbf.Record
+
+// Record returns the attributes of the feature at row. Values are typed
+// according to their fields, see dbf.Reader.Value.
+func (r *Reader) Record(row int) (Record, error) {
+	if err := r.openDbf(); err != nil {
+		return Record{}, err
+	}
+	if err := r.dbf.ReadRecord(row); err != nil {
+		return Record{}, err
+	}
+	return r.dbf.Record()
+}
+
+func (r *Reader) record() (Record, error) {
+	return r.Record(int(r.num) - 1)
+}
+
+func (sr *seqReader) record() (Record, error) {
+	if sr.err != nil {
+		return Record{}, sr.Err()
+	}
+	if sr.dbf == nil {
+		return Record{}, nil
+	}
+	return sr.dbf.Record()
+}
+
+func (zr *ZipReader) record() (Record, error) {
+	return CurrentRecord(zr.sr)
+}
+
+// CurrentRecord returns the attributes of the shape that sr was last advanced
+// to. For implementations of SequentialReader outside of this package, the
+// values are the strings returned by Attribute.
+func CurrentRecord(sr SequentialReader) (Record, error) {
+	if r, ok := sr.(interface {
+		record() (Record, error)
+	}); ok {
+		return r.record()
+	}
+	if err := sr.Err(); err != nil {
+		return Record{}, err
+	}
+	fields := sr.Fields()
+	values := make([]interface{}, len(fields))
+	for i := range values {
+		values[i] = sr.Attribute(i)
+	}
+	return dbf.NewRecord(fields, values)
+}
+
+// WriteRecord writes the attributes in rec into the given row in the DBF.
+// The values are matched to the fields by name, so that attributes can be
+// copied from a shapefile with a different schema. Fields that are missing
+// in rec are not changed.
+func (w *Writer) WriteRecord(row int, rec Record) error {
+	if w.dbf == nil {
+		return fmt.Errorf("%w: initialize it by using SetFields first", ErrNoDBF)
+	}
+	return w.dbf.WriteRecord(row, rec)
+}
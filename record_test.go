@@ -0,0 +1,80 @@
+package shp
+
+import (
+	"testing"
+)
+
+func TestWriteRecord(t *testing.T) {
+	src := filenamePrefix + "record_src"
+	dst := filenamePrefix + "record_dst"
+	defer removeShapefile(src)
+	defer removeShapefile(dst)
+
+	w, err := Create(src+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetFields([]Field{StringField("NAME", 10), NumberField("COUNT", 5), LogicalField("FLAG")})
+	for i, name := range []string{"first", "second"} {
+		n := int(w.Write(&Point{float64(i), float64(i)}))
+		w.WriteAttribute(n, 0, name)
+		w.WriteAttribute(n, 1, 10*i)
+		w.WriteAttribute(n, 2, i == 1)
+	}
+	w.Close()
+
+	r, err := Open(src + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rec, err := r.Record(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := rec.Map(); m["NAME"] != "second" || m["COUNT"] != int64(10) || m["FLAG"] != true {
+		t.Errorf("got record %v", m)
+	}
+	if _, err := r.Record(2); err == nil {
+		t.Error("expected error for row out of range")
+	}
+
+	// copy all features to a shapefile with a different order of fields
+	out, err := Create(dst+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.SetFields([]Field{LogicalField("FLAG"), StringField("NAME", 10)})
+	for r.Next() {
+		_, shape := r.Shape()
+		rec, err := CurrentRecord(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := out.WriteRecord(int(out.Write(shape)), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out.Close()
+
+	sr := SequentialReaderFromExt(openFile(dst+".shp", t), openFile(dst+".dbf", t))
+	defer sr.Close()
+	var got []interface{}
+	for sr.Next() {
+		rec, err := CurrentRecord(sr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec.Value(0), rec.Value(1))
+	}
+	want := []interface{}{false, "first", true, "second"}
+	if len(got) != len(want) {
+		t.Fatalf("got values %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got values %v, want %v", got, want)
+			break
+		}
+	}
+}
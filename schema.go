@@ -0,0 +1,44 @@
+package shp
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
+)
+
+// SchemaOp is an operation that changes the fields of the DBF table of a
+// shapefile. The operations are passed to AlterSchema.
+type SchemaOp = dbf.AlterOp
+
+// AddField returns a SchemaOp that adds field f with blank values.
+func AddField(f Field) SchemaOp {
+	return dbf.AddField(f)
+}
+
+// DropField returns a SchemaOp that removes the field called name.
+func DropField(name string) SchemaOp {
+	return dbf.DropField(name)
+}
+
+// RenameField returns a SchemaOp that renames the field called name to
+// newName.
+func RenameField(name, newName string) SchemaOp {
+	return dbf.RenameField(name, newName)
+}
+
+// ChangeField returns a SchemaOp that replaces the field called name with f,
+// which can be used to resize or retype the field.
+func ChangeField(name string, f Field) SchemaOp {
+	return dbf.ChangeField(name, f)
+}
+
+// AlterSchema changes the fields of the DBF table of the shapefile at
+// filename by applying ops in the given order. The existing values are
+// migrated to the new fields, the SHP and SHX files are not touched. Values
+// that cannot be converted are left blank and reported in a
+// dbf.ConversionErrors error once the table has been rewritten.
+func AlterSchema(filename string, ops ...SchemaOp) error {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return dbf.Alter(base+".dbf", ops...)
+}
@@ -0,0 +1,65 @@
+package shp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestAlterSchema(t *testing.T) {
+	filename := filenamePrefix + "schema"
+	defer removeShapefile(filename)
+
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10), StringField("COUNT", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	for i, count := range []string{"12", "x"} {
+		n := int(w.Write(&Point{float64(i), float64(i)}))
+		w.WriteAttribute(n, 0, "point")
+		w.WriteAttribute(n, 1, count)
+	}
+	w.Close()
+	shp, err := ioutil.ReadFile(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = AlterSchema(filename+".shp",
+		ChangeField("COUNT", NumberField("COUNT", 4)),
+		AddField(FloatField("AREA", 8, 2)),
+	)
+	if err == nil {
+		t.Error("expected conversion error for row 1")
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	fields := r.Fields()
+	if len(fields) != 3 || fields[1].Fieldtype != 'N' || fields[2].String() != "AREA" {
+		t.Fatalf("got fields %v", fields)
+	}
+	if got := r.ReadAttribute(0, 1); got != "12" {
+		t.Errorf("got COUNT %q, want 12", got)
+	}
+	if got := r.ReadAttribute(1, 1); got != "" {
+		t.Errorf("got COUNT %q, want blank value", got)
+	}
+	if got := r.ReadAttribute(1, 0); got != "point" {
+		t.Errorf("got NAME %q, want point", got)
+	}
+
+	after, err := ioutil.ReadFile(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shp, after) {
+		t.Error("SHP file has been changed")
+	}
+}
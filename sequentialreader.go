@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"math"
-	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
 )
 
 // SequentialReader is the interface that allows reading shapes and attributes one after another. It also embeds io.Closer.
@@ -48,6 +48,51 @@ func Attributes(sr SequentialReader) []string {
 	return s
 }
 
+// IsDeleted reports whether the record of the shape that sr was last advanced
+// to is marked as deleted in the DBF table.
+func IsDeleted(sr SequentialReader) bool {
+	if d, ok := sr.(interface {
+		deleted() bool
+	}); ok {
+		return d.deleted()
+	}
+	return false
+}
+
+// DBFHeader returns the header of the DBF table of sr, which holds e.g. the
+// version and the date of the last update. It returns false if sr has no DBF
+// table.
+func DBFHeader(sr SequentialReader) (dbf.Header, bool) {
+	if h, ok := sr.(interface {
+		dbfHeader() (dbf.Header, bool)
+	}); ok {
+		return h.dbfHeader()
+	}
+	return dbf.Header{}, false
+}
+
+// ZRange returns the minimum and maximum Z value of the shapefile that sr
+// reads from its header. It is zero for shape types without Z values.
+func ZRange(sr SequentialReader) [2]float64 {
+	if r, ok := sr.(interface {
+		ZRange() [2]float64
+	}); ok {
+		return r.ZRange()
+	}
+	return [2]float64{}
+}
+
+// MRange returns the minimum and maximum measure of the shapefile that sr
+// reads from its header. It is zero for shape types without measures.
+func MRange(sr SequentialReader) [2]float64 {
+	if r, ok := sr.(interface {
+		MRange() [2]float64
+	}); ok {
+		return r.MRange()
+	}
+	return [2]float64{}
+}
+
 // AttributeCount returns the number of fields of the database.
 func AttributeCount(sr SequentialReader) int {
 	return len(sr.Fields())
@@ -56,26 +101,30 @@ func AttributeCount(sr SequentialReader) int {
 // seqReader implements SequentialReader based on external io.ReadCloser
 // instances
 type seqReader struct {
-	shp, dbf io.ReadCloser
-	err      error
+	shp io.ReadCloser
+	err error
+	readOptions
 
 	geometryType ShapeType
 	bbox         Box
+	zRange       [2]float64
+	mRange       [2]float64
 
 	shape      Shape
 	num        int32
 	filelength int64
+	offset     int64 // position of the next record in the SHP file
+
+	dbf *dbf.Reader
 
-	dbfFields       []Field
-	dbfNumRecords   int32
-	dbfHeaderLength int16
-	dbfRecordLength int16
-	dbfRow          []byte
+	// problems holds the records that were skipped in lenient mode.
+	problems []*FormatError
 }
 
-// Read and parse headers in the Shapefile. This will fill out GeometryType,
-// filelength and bbox.
-func (sr *seqReader) readHeaders() {
+// Read and parse headers in the Shapefile and the DBF file read from dbf,
+// which may be nil. This will fill out GeometryType, filelength, bbox and the
+// Z and M ranges.
+func (sr *seqReader) readHeaders(dbfFile io.ReadCloser) {
 	// contrary to Reader.readHeaders we cannot seek with the ReadCloser, so we
 	// need to trust the filelength in the header
 
@@ -91,96 +140,163 @@ func (sr *seqReader) readHeaders() {
 	sr.bbox.MinY = readFloat64(er)
 	sr.bbox.MaxX = readFloat64(er)
 	sr.bbox.MaxY = readFloat64(er)
-	io.CopyN(ioutil.Discard, er, 32) // skip four float64: Zmin, Zmax, Mmin, Max
+	binary.Read(er, binary.LittleEndian, &sr.zRange)
+	binary.Read(er, binary.LittleEndian, &sr.mRange)
+	sr.offset = er.n
 	if er.e != nil {
-		sr.err = fmt.Errorf("Error when reading SHP header: %v", er.e)
+		sr.err = &FormatError{File: "shp", Offset: 0, Err: truncated(er.e)}
+		if dbfFile != nil {
+			dbfFile.Close()
+		}
 		return
 	}
 
-	// dbf header
-	er = &errReader{Reader: sr.dbf}
-	if sr.dbf == nil {
+	if dbfFile == nil {
 		return
 	}
-	io.CopyN(ioutil.Discard, er, 4)
-	binary.Read(er, binary.LittleEndian, &sr.dbfNumRecords)
-	binary.Read(er, binary.LittleEndian, &sr.dbfHeaderLength)
-	binary.Read(er, binary.LittleEndian, &sr.dbfRecordLength)
-	io.CopyN(ioutil.Discard, er, 20) // skip padding
-	numFields := int(math.Floor(float64(sr.dbfHeaderLength-33) / 32.0))
-	sr.dbfFields = make([]Field, numFields)
-	binary.Read(er, binary.LittleEndian, &sr.dbfFields)
-	buf := make([]byte, 1)
-	er.Read(buf[:])
-	if er.e != nil {
-		sr.err = fmt.Errorf("Error when reading DBF header: %v", er.e)
-		return
-	}
-	if buf[0] != 0x0d {
-		sr.err = fmt.Errorf("Field descriptor array terminator not found")
+	var err error
+	sr.dbf, err = dbf.NewReader(dbfFile)
+	if err != nil {
+		sr.err = err
+		dbfFile.Close()
 		return
 	}
-	sr.dbfRow = make([]byte, sr.dbfRecordLength)
+	sr.dbf.SetLenient(sr.lenient)
 }
 
 // Next implements a method of interface SequentialReader for seqReader.
 func (sr *seqReader) Next() bool {
-	if sr.err != nil {
-		return false
+	for sr.next() {
+		if !sr.skipDeleted || !sr.deleted() {
+			return true
+		}
+	}
+	return false
+}
+
+// next reads the next shape and record regardless of the deletion flag. In
+// lenient mode, bad records are skipped and added to the problems.
+func (sr *seqReader) next() bool {
+	for sr.err == nil {
+		bad, err := sr.readShape()
+		if err == nil {
+			var badRow *FormatError
+			badRow, err = sr.readRow()
+			if bad == nil {
+				bad = badRow
+			}
+		}
+		if err != nil {
+			sr.err = err
+			return false
+		}
+		if bad == nil {
+			return true
+		}
+		sr.problems = append(sr.problems, bad)
 	}
+	return false
+}
+
+// readShape reads the next record. If the record is bad, it returns the
+// problem as error, or in lenient mode, it discards the rest of the record
+// and returns the problem as bad record.
+func (sr *seqReader) readShape() (bad *FormatError, err error) {
 	var num, size int32
 	var shapetype ShapeType
 
 	// read shape
-	er := &errReader{Reader: sr.shp}
+	er := &errReader{Reader: sr.shp, opts: sr.readOptions}
 	binary.Read(er, binary.BigEndian, &num)
 	binary.Read(er, binary.BigEndian, &size)
 	binary.Read(er, binary.LittleEndian, &shapetype)
 
 	if er.e != nil {
-		if er.e != io.EOF {
-			sr.err = fmt.Errorf("Error when reading shapefile header: %v", er.e)
-		} else {
-			sr.err = io.EOF
+		if er.e == io.EOF && er.n == 0 {
+			return nil, io.EOF
 		}
-		return false
+		return nil, &FormatError{File: "shp", Offset: sr.offset, Record: int(num), Err: truncated(er.e)}
 	}
 	sr.num = num
-	var err error
+	if size < 2 {
+		// without content length, the next record cannot be found
+		return nil, &FormatError{File: "shp", Offset: sr.offset, Record: int(num),
+			Err: fmt.Errorf("%w %d", ErrInvalidContentLength, size)}
+	}
+	length := int64(size)*2 + 8
 	sr.shape, err = newShape(shapetype)
-	if err != nil {
-		sr.err = fmt.Errorf("Error decoding shape type: %v", err)
-		return false
-	}
-	sr.shape.read(er)
-	switch {
-	case er.e == io.EOF:
-		// io.EOF means end-of-file was reached gracefully after all
-		// shape-internal reads succeeded, so it's not a reason stop
-		// iterating over all shapes.
-		er.e = nil
-	case er.e != nil:
-		sr.err = fmt.Errorf("Error while reading next shape: %v", er.e)
-		return false
-	}
-	skipBytes := int64(size)*2 + 8 - er.n
-	_, ce := io.CopyN(ioutil.Discard, er, skipBytes)
-	if er.e != nil {
-		sr.err = er.e
-		return false
+	if err == nil && sr.enforceType && shapetype != NULL && shapetype != sr.geometryType {
+		err = mismatch(shapetype, sr.geometryType)
+	}
+	if err == nil {
+		err = readContent(sr.shape, er, length)
+	}
+	if err != nil && !sr.lenient {
+		return nil, &FormatError{File: "shp", Offset: sr.offset, Record: int(num), Err: err}
 	}
+
+	// skip the rest of the record
+	_, ce := io.CopyN(ioutil.Discard, sr.shp, length-er.n)
 	if ce != nil {
-		sr.err = fmt.Errorf("Error when discarding bytes on sequential read: %v", ce)
-		return false
+		return nil, &FormatError{File: "shp", Offset: sr.offset, Record: int(num), Err: truncated(ce)}
 	}
-	if _, err := io.ReadFull(sr.dbf, sr.dbfRow); err != nil {
-		sr.err = fmt.Errorf("Error when reading DBF row: %v", err)
-		return false
+	sr.offset += length
+	if err != nil {
+		return &FormatError{File: "shp", Offset: sr.offset - length, Record: int(num), Err: err}, nil
 	}
-	if sr.dbfRow[0] != 0x20 && sr.dbfRow[0] != 0x2a {
-		sr.err = fmt.Errorf("Attribute row %d starts with incorrect deletion indicator", num)
+	return nil, nil
+}
+
+// readRow advances the DBF table to the record of the current shape. In
+// lenient mode, a record with an invalid deletion indicator is returned as
+// bad record.
+func (sr *seqReader) readRow() (bad *FormatError, err error) {
+	if sr.dbf == nil {
+		return nil, nil
+	}
+	h := sr.dbf.Header()
+	if !sr.dbf.Next() {
+		fe := &FormatError{File: "dbf", Offset: -1, Record: int(sr.num), Err: sr.dbf.Err()}
+		if fe.Err == nil {
+			// the table ended early, so the record is missing at its end
+			fe.Offset = int64(h.HeaderLength) + int64(h.NumRecords)*int64(h.RecordLength)
+			fe.Err = ErrRecordMismatch
+		}
+		return nil, fe
+	}
+	if err := sr.dbf.RecordErr(); err != nil {
+		row := sr.dbf.Row()
+		return &FormatError{File: "dbf", Offset: int64(h.HeaderLength) + int64(row)*int64(h.RecordLength),
+			Record: row + 1, Err: err}, nil
+	}
+	return nil, nil
+}
+
+// ZRange returns the range of Z values from the header.
+func (sr *seqReader) ZRange() [2]float64 {
+	return sr.zRange
+}
+
+// MRange returns the range of measures from the header.
+func (sr *seqReader) MRange() [2]float64 {
+	return sr.mRange
+}
+
+// Problems returns the records that were skipped in lenient mode.
+func (sr *seqReader) Problems() []*FormatError {
+	return sr.problems
+}
+
+// deleted reports whether the current record is marked as deleted.
+func (sr *seqReader) deleted() bool {
+	return sr.dbf != nil && sr.dbf.Deleted()
+}
+
+func (sr *seqReader) dbfHeader() (dbf.Header, bool) {
+	if sr.dbf == nil {
+		return dbf.Header{}, false
 	}
-	return sr.err == nil
+	return sr.dbf.Header(), true
 }
 
 // Shape implements a method of interface SequentialReader for seqReader.
@@ -190,16 +306,10 @@ func (sr *seqReader) Shape() (int, Shape) {
 
 // Attribute implements a method of interface SequentialReader for seqReader.
 func (sr *seqReader) Attribute(n int) string {
-	if sr.err != nil {
+	if sr.err != nil || sr.dbf == nil {
 		return ""
 	}
-	start := 1
-	f := 0
-	for ; f < n; f++ {
-		start += int(sr.dbfFields[f].Size)
-	}
-	s := string(sr.dbfRow[start : start+int(sr.dbfFields[f].Size)])
-	return strings.Trim(s, " ")
+	return sr.dbf.Attribute(n)
 }
 
 // Err returns the first non-EOF error that was encountered.
@@ -211,25 +321,66 @@ func (sr *seqReader) Err() error {
 }
 
 // Close closes the seqReader and free all the allocated resources.
+// setMemo attaches the memo file m to the DBF table, see MemoFile. m is closed
+// if there is no table.
+func (sr *seqReader) setMemo(m io.ReadCloser, foxPro bool) {
+	if sr.dbf == nil || sr.err != nil {
+		m.Close()
+		return
+	}
+	rs, ok := m.(io.ReadSeeker)
+	if !ok {
+		s, err := spill(m, -1)
+		m.Close()
+		if err != nil {
+			sr.err = err
+			return
+		}
+		rs, m = s, s
+	}
+	if err := sr.dbf.SetMemo(rs, foxPro); err != nil {
+		sr.err = err
+		m.Close()
+	}
+}
+
+// openMemo attaches the memo file of the DBF table, which is opened by
+// passing its extension to open, see memoFile.
+func (sr *seqReader) openMemo(open func(ext string) (readSeekCloser, error)) {
+	if sr.err != nil {
+		return
+	}
+	if m, foxPro := memoFile(sr.dbf, open); m != nil {
+		sr.setMemo(m, foxPro)
+	}
+}
+
 func (sr *seqReader) Close() error {
 	if err := sr.shp.Close(); err != nil {
 		return err
 	}
-	if err := sr.dbf.Close(); err != nil {
-		return err
+	if sr.dbf == nil {
+		return nil
 	}
-	return nil
+	return sr.dbf.Close()
 }
 
 // Fields returns a slice of the fields that are present in the DBF table.
 func (sr *seqReader) Fields() []Field {
-	return sr.dbfFields
+	if sr.dbf == nil {
+		return nil
+	}
+	return sr.dbf.Fields()
 }
 
 // SequentialReaderFromExt returns a new SequentialReader that interprets shp
-// as a source of shapes whose attributes can be retrieved from dbf.
-func SequentialReaderFromExt(shp, dbf io.ReadCloser) SequentialReader {
-	sr := &seqReader{shp: shp, dbf: dbf}
-	sr.readHeaders()
+// as a source of shapes whose attributes can be retrieved from dbf. The memo
+// file of dbf can be passed with MemoFile.
+func SequentialReaderFromExt(shp, dbf io.ReadCloser, opts ...ReadOption) SequentialReader {
+	sr := &seqReader{shp: shp, readOptions: newReadOptions(opts)}
+	sr.readHeaders(dbf)
+	if sr.memo != nil {
+		sr.setMemo(sr.memo, sr.memoFoxPro)
+	}
 	return sr
 }
@@ -1,7 +1,13 @@
 package shp
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -41,3 +47,103 @@ func TestSequentialReader(t *testing.T) {
 		testshapeIdentity(t, prefix, getShapesSequentially)
 	}
 }
+
+func TestSequentialReaderMemo(t *testing.T) {
+	filename := filenamePrefix + "seq_memo"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{MemoField("NOTES")}); err != nil {
+		t.Fatal(err)
+	}
+	notes := []string{"first note", "", "third note"}
+	for i, note := range notes {
+		n := int(w.Write(&Point{float64(i), float64(i)}))
+		if err := w.WriteAttribute(n, 0, note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "go-shp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	zipName := filepath.Join(dir, "memo.zip")
+	zf, err := os.Create(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	tarName := filepath.Join(dir, "memo.tar")
+	tf, err := os.Create(tarName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(tf)
+	for _, ext := range []string{".shp", ".shx", ".dbf", ".dbt"} {
+		compressFileToZIP(zw, filename+ext, "memo"+ext, zip.Deflate, t)
+		b, err := ioutil.ReadFile(filename + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := &tar.Header{Name: "memo" + ext, Mode: 0644, Size: int64(len(b)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []io.Closer{zw, zf, tw, tf} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := OpenDataset(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	readers := map[string]func() (SequentialReader, error){
+		"ext": func() (SequentialReader, error) {
+			b, err := ioutil.ReadFile(filename + ".dbt")
+			if err != nil {
+				return nil, err
+			}
+			// a memo file that cannot seek
+			m := ioutil.NopCloser(bytes.NewReader(b))
+			return SequentialReaderFromExt(openFile(filename+".shp", t), openFile(filename+".dbf", t), MemoFile(m, false)), nil
+		},
+		"zip": func() (SequentialReader, error) {
+			return OpenZip(zipName)
+		},
+		"tar": func() (SequentialReader, error) {
+			return OpenTar(tarName)
+		},
+		"dataset": func() (SequentialReader, error) {
+			return d.OpenSequential("memo")
+		},
+	}
+	for name, open := range readers {
+		sr, err := open()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i := 0; sr.Next(); i++ {
+			if got := sr.Attribute(0); got != notes[i] {
+				t.Errorf("%s: row %d: got %q, want %q", name, i, got, notes[i])
+			}
+		}
+		if err := sr.Err(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		sr.Close()
+	}
+}
@@ -3,7 +3,8 @@ package shp
 import (
 	"encoding/binary"
 	"io"
-	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
 )
 
 //go:generate stringer -type=ShapeType
@@ -76,6 +77,18 @@ func BBoxFromPoints(points []Point) (box Box) {
 // Shape interface
 type Shape interface {
 	BBox() Box
+	// ShapeType returns the type of the records in which the shape is
+	// stored.
+	ShapeType() ShapeType
+	// PartCount returns the number of parts of the shape. Points are a
+	// single part and so are the points of the multi-point types.
+	PartCount() int
+	// Part returns the coordinates of the i-th part of the shape. It panics
+	// if i is out of range.
+	Part(i int) []Coord
+	// Coords returns the coordinates of all points of the shape. Changing
+	// them does not change the shape.
+	Coords() []Coord
 
 	read(io.Reader)
 	write(io.Writer)
@@ -174,9 +187,15 @@ func (p *PolyLine) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 4, 16) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.Points)
 }
 
@@ -201,9 +220,15 @@ func (p *Polygon) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 4, 16) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.Points)
 }
 
@@ -230,6 +255,9 @@ func (p MultiPoint) BBox() Box {
 func (p *MultiPoint) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, 0, p.NumPoints, 0, 16) {
+		return
+	}
 	p.Points = make([]Point, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Points)
 }
@@ -254,12 +282,28 @@ func (p PointZ) BBox() Box {
 	return Box{p.X, p.Y, p.X, p.Y}
 }
 
+// HasM reports whether the PointZ has a measure that does not signal "no
+// data". Without measure, the M value of the record is omitted.
+func (p PointZ) HasM() bool {
+	return !IsNoData(p.M)
+}
+
 func (p *PointZ) read(file io.Reader) {
-	binary.Read(file, binary.LittleEndian, p)
+	binary.Read(file, binary.LittleEndian, &p.X)
+	binary.Read(file, binary.LittleEndian, &p.Y)
+	binary.Read(file, binary.LittleEndian, &p.Z)
+	p.M = NoData
+	if fits(file, 8) {
+		binary.Read(file, binary.LittleEndian, &p.M)
+	}
 }
 
 func (p *PointZ) write(file io.Writer) {
-	binary.Write(file, binary.LittleEndian, p)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p)
+	} else {
+		binary.Write(file, binary.LittleEndian, []float64{p.X, p.Y, p.Z})
+	}
 }
 
 // PolyLineZ is a shape which consists of one or more parts. A part is a
@@ -282,18 +326,35 @@ func (p PolyLineZ) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the PolyLineZ has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p PolyLineZ) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *PolyLineZ) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 4, 24) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
 	p.ZArray = make([]float64, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.Points)
 	binary.Read(file, binary.LittleEndian, &p.ZRange)
 	binary.Read(file, binary.LittleEndian, &p.ZArray)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -306,8 +367,10 @@ func (p *PolyLineZ) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.Points)
 	binary.Write(file, binary.LittleEndian, p.ZRange)
 	binary.Write(file, binary.LittleEndian, p.ZArray)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
 // PolygonZ structure is identical to the PolyLineZ structure.
@@ -318,18 +381,35 @@ func (p PolygonZ) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the PolygonZ has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p PolygonZ) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *PolygonZ) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 4, 24) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
 	p.ZArray = make([]float64, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.Points)
 	binary.Read(file, binary.LittleEndian, &p.ZRange)
 	binary.Read(file, binary.LittleEndian, &p.ZArray)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -342,8 +422,10 @@ func (p *PolygonZ) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.Points)
 	binary.Write(file, binary.LittleEndian, p.ZRange)
 	binary.Write(file, binary.LittleEndian, p.ZArray)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
 // MultiPointZ consists of one ore more PointZ.
@@ -362,15 +444,29 @@ func (p MultiPointZ) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the MultiPointZ has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p MultiPointZ) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *MultiPointZ) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, 0, p.NumPoints, 0, 24) {
+		return
+	}
 	p.Points = make([]Point, p.NumPoints)
 	p.ZArray = make([]float64, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Points)
 	binary.Read(file, binary.LittleEndian, &p.ZRange)
 	binary.Read(file, binary.LittleEndian, &p.ZArray)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -381,8 +477,10 @@ func (p *MultiPointZ) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.Points)
 	binary.Write(file, binary.LittleEndian, p.ZRange)
 	binary.Write(file, binary.LittleEndian, p.ZArray)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
 // PointM is a point with a measure.
@@ -422,15 +520,32 @@ func (p PolyLineM) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the PolyLineM has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p PolyLineM) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *PolyLineM) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 4, 16) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.Points)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -441,27 +556,67 @@ func (p *PolyLineM) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.NumPoints)
 	binary.Write(file, binary.LittleEndian, p.Parts)
 	binary.Write(file, binary.LittleEndian, p.Points)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
-// PolygonM structure is identical to the PolyLineZ structure.
-type PolygonM PolyLineZ
+// PolygonM is identical to the PolyLineM struct. However the parts must form
+// rings that may not intersect. Earlier versions based PolygonM on PolyLineZ,
+// whose Z fields were never read or written; use PolygonMFromZ to convert
+// shapes that were built that way.
+type PolygonM PolyLineM
+
+// PolygonMFromZ returns the PolygonM with the coordinates and measures of p,
+// which has been built for the former layout of PolygonM. The Z values of p
+// are dropped, as they have never been written for a PolygonM.
+//
+// Deprecated: Build a PolygonM directly with the fields of PolyLineM or use
+// NewPolygonM.
+func PolygonMFromZ(p *PolyLineZ) *PolygonM {
+	return &PolygonM{
+		Box:       p.Box,
+		NumParts:  p.NumParts,
+		NumPoints: p.NumPoints,
+		Parts:     p.Parts,
+		Points:    p.Points,
+		MRange:    p.MRange,
+		MArray:    p.MArray,
+	}
+}
 
 // BBox returns the bounding box of the PolygonM feature.
 func (p PolygonM) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the PolygonM has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p PolygonM) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *PolygonM) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 4, 16) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.Points)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -472,8 +627,10 @@ func (p *PolygonM) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.NumPoints)
 	binary.Write(file, binary.LittleEndian, p.Parts)
 	binary.Write(file, binary.LittleEndian, p.Points)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
 // MultiPointM is the collection of multiple points with measures.
@@ -490,12 +647,26 @@ func (p MultiPointM) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the MultiPointM has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p MultiPointM) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *MultiPointM) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, 0, p.NumPoints, 0, 16) {
+		return
+	}
 	p.Points = make([]Point, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Points)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -504,8 +675,10 @@ func (p *MultiPointM) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.Box)
 	binary.Write(file, binary.LittleEndian, p.NumPoints)
 	binary.Write(file, binary.LittleEndian, p.Points)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
 // MultiPatch consists of a number of surfaces patches. Each surface path
@@ -530,20 +703,37 @@ func (p MultiPatch) BBox() Box {
 	return BBoxFromPoints(p.Points)
 }
 
+// HasM reports whether the MultiPatch has measures. Measures that signal "no
+// data" do not count. Without measures, the M block of the record is omitted,
+// and a record without it is read with NoData measures.
+func (p MultiPatch) HasM() bool {
+	return hasM(p.MArray)
+}
+
 func (p *MultiPatch) read(file io.Reader) {
 	binary.Read(file, binary.LittleEndian, &p.Box)
 	binary.Read(file, binary.LittleEndian, &p.NumParts)
 	binary.Read(file, binary.LittleEndian, &p.NumPoints)
+	if !checkCounts(file, p.NumParts, p.NumPoints, 8, 24) {
+		return
+	}
 	p.Parts = make([]int32, p.NumParts)
 	p.PartTypes = make([]int32, p.NumParts)
 	p.Points = make([]Point, p.NumPoints)
 	p.ZArray = make([]float64, p.NumPoints)
-	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.Parts)
+	if !checkParts(file, p.Parts, p.NumPoints) {
+		return
+	}
 	binary.Read(file, binary.LittleEndian, &p.PartTypes)
 	binary.Read(file, binary.LittleEndian, &p.Points)
 	binary.Read(file, binary.LittleEndian, &p.ZRange)
 	binary.Read(file, binary.LittleEndian, &p.ZArray)
+	if !hasMeasures(file, p.NumPoints) {
+		p.MArray = noMeasures(p.NumPoints)
+		return
+	}
+	p.MArray = make([]float64, p.NumPoints)
 	binary.Read(file, binary.LittleEndian, &p.MRange)
 	binary.Read(file, binary.LittleEndian, &p.MArray)
 }
@@ -557,56 +747,80 @@ func (p *MultiPatch) write(file io.Writer) {
 	binary.Write(file, binary.LittleEndian, p.Points)
 	binary.Write(file, binary.LittleEndian, p.ZRange)
 	binary.Write(file, binary.LittleEndian, p.ZArray)
-	binary.Write(file, binary.LittleEndian, p.MRange)
-	binary.Write(file, binary.LittleEndian, p.MArray)
+	if p.HasM() {
+		binary.Write(file, binary.LittleEndian, p.MRange)
+		binary.Write(file, binary.LittleEndian, p.MArray)
+	}
 }
 
-// Field representation of a field object in the DBF file
-type Field struct {
-	Name      [11]byte
-	Fieldtype byte
-	Addr      [4]byte // not used
-	Size      uint8
-	Precision uint8
-	Padding   [14]byte
-}
-
-// Returns a string representation of the Field. Currently
-// this only returns field name.
-func (f Field) String() string {
-	return strings.TrimRight(string(f.Name[:]), "\x00")
-}
+// Field representation of a field object in the DBF file. See the dbf
+// package for reading and writing tables without geometry.
+type Field = dbf.Field
 
 // StringField returns a Field that can be used in SetFields to initialize the
 // DBF file.
 func StringField(name string, length uint8) Field {
-	// TODO: Error checking
-	field := Field{Fieldtype: 'C', Size: length}
-	copy(field.Name[:], []byte(name))
-	return field
+	return dbf.StringField(name, length)
 }
 
 // NumberField returns a Field that can be used in SetFields to initialize the
 // DBF file.
 func NumberField(name string, length uint8) Field {
-	field := Field{Fieldtype: 'N', Size: length}
-	copy(field.Name[:], []byte(name))
-	return field
+	return dbf.NumberField(name, length)
 }
 
 // FloatField returns a Field that can be used in SetFields to initialize the
 // DBF file. Used to store floating points with precision in the DBF.
 func FloatField(name string, length uint8, precision uint8) Field {
-	field := Field{Fieldtype: 'F', Size: length, Precision: precision}
-	copy(field.Name[:], []byte(name))
-	return field
+	return dbf.FloatField(name, length, precision)
 }
 
 // DateField feturns a Field that can be used in SetFields to initialize the
 // DBF file. Used to store Date strings formatted as YYYYMMDD. Data wise this
 // is the same as a StringField with length 8.
 func DateField(name string) Field {
-	field := Field{Fieldtype: 'D', Size: 8}
-	copy(field.Name[:], []byte(name))
-	return field
+	return dbf.DateField(name)
+}
+
+// LogicalField returns a Field that can be used in SetFields to initialize the
+// DBF file. Used to store boolean values.
+func LogicalField(name string) Field {
+	return dbf.LogicalField(name)
+}
+
+// IntegerField returns a Field that can be used in SetFields to initialize the
+// DBF file. Used to store 32-bit integers in binary form.
+func IntegerField(name string) Field {
+	return dbf.IntegerField(name)
+}
+
+// DoubleField returns a Field that can be used in SetFields to initialize the
+// DBF file. Used to store 64-bit floating points in binary form.
+func DoubleField(name string) Field {
+	return dbf.DoubleField(name)
+}
+
+// TimestampField returns a Field that can be used in SetFields to initialize
+// the DBF file. Used to store dates with time of day as in dBase 7.
+func TimestampField(name string) Field {
+	return dbf.TimestampField(name)
+}
+
+// DateTimeField returns a Field that can be used in SetFields to initialize
+// the DBF file. Used to store dates with time of day as in FoxPro.
+func DateTimeField(name string) Field {
+	return dbf.DateTimeField(name)
+}
+
+// CurrencyField returns a Field that can be used in SetFields to initialize
+// the DBF file. Used to store monetary values with four decimals.
+func CurrencyField(name string) Field {
+	return dbf.CurrencyField(name)
+}
+
+// MemoField returns a Field that can be used in SetFields to initialize the
+// DBF file. Used to store texts of any length in a .dbt memo file, which is
+// created next to the DBF file.
+func MemoField(name string) Field {
+	return dbf.MemoField(name)
 }
@@ -20,3 +20,22 @@ func TestBoxExtend(t *testing.T) {
 		t.Errorf("a.MaxY = %v, want %v", a.MaxY, c.MaxY)
 	}
 }
+
+func TestPolygonMFromZ(t *testing.T) {
+	z := &PolyLineZ{
+		NumParts:  1,
+		NumPoints: 2,
+		Parts:     []int32{0},
+		Points:    []Point{{0, 0}, {1, 1}},
+		ZArray:    []float64{7, 8},
+		MRange:    [2]float64{1, 2},
+		MArray:    []float64{1, 2},
+	}
+	p := PolygonMFromZ(z)
+	if p.NumPoints != 2 || len(p.Points) != 2 || p.MRange != z.MRange || len(p.MArray) != 2 {
+		t.Errorf("got %+v", p)
+	}
+	if m := (*PolyLineM)(p); m.NumParts != 1 {
+		t.Errorf("PolygonM does not have the layout of PolyLineM: %+v", m)
+	}
+}
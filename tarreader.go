@@ -0,0 +1,201 @@
+package shp
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// OpenTar opens a tar archive that contains a single shapefile. Archives that
+// are compressed with gzip (.tar.gz, .tgz) are detected automatically. As tar
+// archives can only be read sequentially, the SHP, DBF and memo files are
+// copied into memory or temporary files, which are removed when the returned
+// SequentialReader is closed.
+func OpenTar(tarFilePath string, opts ...ReadOption) (SequentialReader, error) {
+	all := func(name string) bool { return true }
+	return shapeFromTar(tarFilePath, all, singleLayer, opts)
+}
+
+// OpenShapeFromTar opens the shapefile called name that is contained in the
+// (optionally gzip-compressed) tar archive at tarFilePath.
+func OpenShapeFromTar(tarFilePath string, name string, opts ...ReadOption) (SequentialReader, error) {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	sameBase := func(member string) bool {
+		return strings.EqualFold(strings.TrimSuffix(member, path.Ext(member)), base)
+	}
+	byName := func(layers []archiveLayer) (archiveLayer, error) {
+		return layerByName(layers, name)
+	}
+	return shapeFromTar(tarFilePath, sameBase, byName, opts)
+}
+
+// ShapesInTar returns the names of all shapes that are in the (optionally
+// gzip-compressed) tar archive at tarFilePath. The same rules as in
+// ShapesInZip apply.
+func ShapesInTar(tarFilePath string) ([]string, error) {
+	names, err := tarMembers(tarFilePath)
+	if err != nil {
+		return nil, err
+	}
+	var shapes []string
+	for _, l := range findLayers(names) {
+		shapes = append(shapes, l.name)
+	}
+	return shapes, nil
+}
+
+// gzipFile is a gzip-compressed file that is opened for reading.
+type gzipFile struct {
+	io.Reader
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	return g.f.Close()
+}
+
+// openMaybeGzipped opens the file at p for reading and transparently
+// decompresses it if it starts with the gzip magic number.
+func openMaybeGzipped(p string) (io.ReadCloser, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return gzipFile{br, f}, nil
+	}
+	gr, err := gzip.NewReader(br)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipFile{gr, f}, nil
+}
+
+// walkTar calls fn for every regular file in the tar archive at p. The index
+// counts the regular files only.
+func walkTar(p string, fn func(i int, h *tar.Header, r io.Reader) error) error {
+	rc, err := openMaybeGzipped(p)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for i := 0; ; {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// archive/tar reports the obsolete TypeRegA as TypeReg
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(i, h, tr); err != nil {
+			return err
+		}
+		i++
+	}
+}
+
+// tarMembers returns the names of all regular files in the tar archive at p.
+func tarMembers(p string) ([]string, error) {
+	var names []string
+	err := walkTar(p, func(i int, h *tar.Header, r io.Reader) error {
+		names = append(names, strings.TrimPrefix(h.Name, "./"))
+		return nil
+	})
+	return names, err
+}
+
+// shapeFromTar reads the tar archive at p in a single pass. It extracts the
+// SHP, DBF and memo files whose names are accepted by want, and then opens the layer
+// that pick chooses from all layers in the archive. The extracted files of
+// other layers are discarded.
+func shapeFromTar(p string, want func(name string) bool,
+	pick func([]archiveLayer) (archiveLayer, error), opts []ReadOption) (SequentialReader, error) {
+	var names []string
+	files := make(map[int]readSeekCloser)
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	err := walkTar(p, func(i int, h *tar.Header, r io.Reader) error {
+		name := strings.TrimPrefix(h.Name, "./")
+		names = append(names, name)
+		ext := strings.ToLower(path.Ext(name))
+		if (ext != ".shp" && ext != ".dbf" && ext != ".dbt" && ext != ".fpt") || ignoreArchiveMember(name) || !want(name) {
+			return nil
+		}
+		f, err := spill(r, h.Size)
+		if err != nil {
+			return err
+		}
+		files[i] = f
+		return nil
+	})
+	if err != nil {
+		closeFiles()
+		return nil, err
+	}
+	l, err := pick(findLayers(names))
+	if err != nil {
+		closeFiles()
+		return nil, err
+	}
+	shp, ok := files[l.files[".shp"]]
+	if !ok {
+		closeFiles()
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, l.name)
+	}
+	delete(files, l.files[".shp"])
+	var dbf readSeekCloser
+	if i, ok := l.files[".dbf"]; ok {
+		dbf = files[i]
+		delete(files, i)
+	}
+	sr := &seqReader{shp: shp, readOptions: newReadOptions(opts)}
+	sr.readHeaders(dbf)
+	sr.openMemo(layerOpener(l, func(i int) (readSeekCloser, error) {
+		f, ok := files[i]
+		if !ok {
+			return nil, ErrFileNotFound
+		}
+		delete(files, i)
+		return f, nil
+	}))
+	closeFiles()
+	return sr, nil
+}
+
+// OpenGzip opens a shapefile whose SHP file at shpFilePath is compressed with
+// gzip, e.g. roads.shp.gz. The DBF file is looked up next to it as roads.dbf.gz
+// or, if that does not exist, as the uncompressed roads.dbf.
+func OpenGzip(shpFilePath string, opts ...ReadOption) (SequentialReader, error) {
+	base := shpFilePath
+	if strings.ToLower(filepath.Ext(base)) == ".gz" {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	shp, err := openMaybeGzipped(shpFilePath)
+	if err != nil {
+		return nil, err
+	}
+	// dbf is optional, so no error checking here
+	dbf, err := openMaybeGzipped(base + ".dbf.gz")
+	if err != nil {
+		dbf, _ = openMaybeGzipped(base + ".dbf")
+	}
+	return SequentialReaderFromExt(shp, dbf, opts...), nil
+}
@@ -0,0 +1,154 @@
+package shp
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createTempTar packs the SHP, SHX, and DBF files of all prefixes into a tar
+// archive in a temporary directory. The archive is compressed with gzip if
+// compress is true.
+func createTempTar(prefixes []string, compress bool, t *testing.T) (dir, filename string) {
+	dir, err := ioutil.TempDir("", "go-shp-test")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %v", err)
+	}
+	filename = filepath.Join(dir, "shapes.tar")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("Could not create temporary tar file: %v", err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if compress {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	for _, prefix := range prefixes {
+		for _, suffix := range []string{".shp", ".shx", ".dbf"} {
+			b, err := ioutil.ReadFile(prefix + suffix)
+			if err != nil {
+				t.Fatalf("Could not read %s: %v", prefix+suffix, err)
+			}
+			h := &tar.Header{
+				Name:     "shapes/" + filepath.Base(prefix) + suffix,
+				Mode:     0644,
+				Size:     int64(len(b)),
+				Typeflag: tar.TypeReg,
+			}
+			if err := tw.WriteHeader(h); err != nil {
+				t.Fatalf("Could not write tar header: %v", err)
+			}
+			if _, err := tw.Write(b); err != nil {
+				t.Fatalf("Could not write tar contents: %v", err)
+			}
+		}
+	}
+	return dir, filename
+}
+
+func readAllShapes(sr SequentialReader, t *testing.T) (shapes []Shape) {
+	for sr.Next() {
+		_, shape := sr.Shape()
+		shapes = append(shapes, shape)
+	}
+	if err := sr.Err(); err != nil {
+		t.Errorf("Error when iterating over the shapes: %v", err)
+	}
+	if err := sr.Close(); err != nil {
+		t.Errorf("Could not close reader: %v", err)
+	}
+	return shapes
+}
+
+func TestTarReader(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		for prefix := range dataForReadTests {
+			testshapeIdentity(t, prefix, func(prefix string, t *testing.T) []Shape {
+				dir, filename := createTempTar([]string{prefix}, compress, t)
+				defer os.RemoveAll(dir)
+				sr, err := OpenTar(filename)
+				if err != nil {
+					t.Fatalf("Error when opening tar file: %v", err)
+				}
+				return readAllShapes(sr, t)
+			})
+		}
+	}
+}
+
+func TestShapesInTar(t *testing.T) {
+	dir, filename := createTempTar([]string{"test_files/point", "test_files/polygon"}, true, t)
+	defer os.RemoveAll(dir)
+	names, err := ShapesInTar(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "shapes/point.shp" || names[1] != "shapes/polygon.shp" {
+		t.Fatalf("got shapes %v, want [shapes/point.shp shapes/polygon.shp]", names)
+	}
+	if _, err := OpenTar(filename); err == nil {
+		t.Fatal("opened archive with multiple shapefiles without error")
+	}
+	sr, err := OpenShapeFromTar(filename, "shapes/polygon.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sr.Fields()) != 2 {
+		t.Errorf("got %d fields, want 2", len(sr.Fields()))
+	}
+	testPolygon(t, dataForReadTests["test_files/polygon"].points, readAllShapes(sr, t))
+}
+
+func gzipFileTo(src, tgt string, t *testing.T) {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatalf("Could not read %s: %v", src, err)
+	}
+	f, err := os.Create(tgt)
+	if err != nil {
+		t.Fatalf("Could not create %s: %v", tgt, err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+	if _, err := gw.Write(b); err != nil {
+		t.Fatalf("Could not compress %s: %v", src, err)
+	}
+}
+
+func TestOpenGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-test")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	gzipFileTo("test_files/point.shp", filepath.Join(dir, "point.shp.gz"), t)
+	gzipFileTo("test_files/point.dbf", filepath.Join(dir, "point.dbf.gz"), t)
+	sr, err := OpenGzip(filepath.Join(dir, "point.shp.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sr.Fields()) != 1 {
+		t.Errorf("got %d fields, want 1", len(sr.Fields()))
+	}
+	testPoint(t, dataForReadTests["test_files/point"].points, readAllShapes(sr, t))
+
+	// without any DBF file
+	os.Remove(filepath.Join(dir, "point.dbf.gz"))
+	sr, err = OpenGzip(filepath.Join(dir, "point.shp.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(readAllShapes(sr, t)); got != 3 {
+		t.Errorf("got %d shapes, want 3", got)
+	}
+}
@@ -0,0 +1,157 @@
+package shp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OpenForUpdate returns a Writer for editing the existing shapefile at
+// filename. Like with Append, new shapes can be added to the end of the
+// file, and in addition, existing features can be changed with
+// UpdateAttribute, Delete and ReplaceShape. The shapefile must have a valid
+// index file. Close must be called when done because it fixes the headers.
+func OpenForUpdate(filename string) (*Writer, error) {
+	return Append(filename)
+}
+
+// UpdateAttribute overwrites the value of field in the existing row of the
+// DBF table. It accepts the same values as WriteAttribute.
+func (w *Writer) UpdateAttribute(row int, field int, value interface{}) error {
+	if w.dbf == nil {
+		return ErrNoDBF
+	}
+	if row < 0 || row >= w.dbf.NumRecords() {
+		return fmt.Errorf("DBF row %d out of range", row)
+	}
+	return w.dbf.WriteAttribute(row, field, value)
+}
+
+// Delete marks the feature at row as deleted in the DBF table. The feature
+// stays in the shapefile until it is removed with Pack.
+func (w *Writer) Delete(row int) error {
+	if w.dbf == nil {
+		return ErrNoDBF
+	}
+	return w.dbf.Delete(row)
+}
+
+// ReplaceShape replaces the geometry of the feature at row. If the new
+// record has the same length as the old one, it is written in place.
+// Otherwise it is written to the end of the SHP file, the old record is turned into a null shape and the SHX file is
+// updated to point to the new record. Readers that do not use the SHX file,
+// such as the SequentialReader, see the null shape at the old position; Pack
+// restores the order of the records. The bounding box in the headers is
+// recalculated by Close.
+func (w *Writer) ReplaceShape(row int, shape Shape) error {
+	shp, ok1 := w.shp.(io.ReadWriteSeeker)
+	shx, ok2 := w.shx.(io.ReadWriteSeeker)
+	if !ok1 || !ok2 {
+		return errors.New("Shapefile is not opened for update")
+	}
+	if row < 0 || row >= int(w.num) {
+		return fmt.Errorf("Shape %d out of range", row)
+	}
+	shape, t, err := w.checkShape(shape)
+	if err != nil {
+		return err
+	}
+	offset, length, err := readIndex(shx, row)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, t)
+	shape.write(&buf)
+	content := buf.Bytes()
+
+	if int64(len(content)) == length {
+		if _, err := shp.Seek(offset+8, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := shp.Write(content)
+		w.recalcBBox = true
+		return err
+	}
+
+	// Replace the old record with a null shape of the same length. A shorter
+	// record cannot be padded in place, because readers would take the
+	// padding for optional parts such as the M block.
+	if _, err := shp.Seek(offset+8, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := shp.Write(make([]byte, length)); err != nil {
+		return err
+	}
+
+	end, err := shp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	binary.Write(shp, binary.BigEndian, []int32{int32(row + 1), int32(len(content) / 2)})
+	if _, err := shp.Write(content); err != nil {
+		return err
+	}
+	if _, err := shx.Seek(100+int64(row)*8, io.SeekStart); err != nil {
+		return err
+	}
+	err = binary.Write(shx, binary.BigEndian, []int32{int32(end / 2), int32(len(content) / 2)})
+	w.recalcBBox = true
+
+	// new shapes are appended to the end of both files
+	shp.Seek(0, io.SeekEnd)
+	shx.Seek(0, io.SeekEnd)
+	return err
+}
+
+// readIndex returns the offset of the record at row in the SHP file and the
+// length of its content in bytes from the SHX file read from shx.
+func readIndex(shx io.ReadSeeker, row int) (offset int64, length int64, err error) {
+	if _, err := shx.Seek(100+int64(row)*8, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	var entry [2]int32
+	if err := binary.Read(shx, binary.BigEndian, &entry); err != nil {
+		return 0, 0, &FormatError{File: "shx", Offset: 100 + int64(row)*8, Record: row + 1, Err: truncated(err)}
+	}
+	return int64(entry[0]) * 2, int64(entry[1]) * 2, nil
+}
+
+// readRecord returns the content of the record at row of the SHP file read
+// from shp using the index read from shx.
+func readRecord(shp, shx io.ReadSeeker, row int) ([]byte, error) {
+	offset, length, err := readIndex(shx, row)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := shp.Seek(offset+8, io.SeekStart); err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(shp, content); err != nil {
+		return nil, &FormatError{File: "shp", Offset: offset, Record: row + 1, Err: truncated(err)}
+	}
+	return content, nil
+}
+
+// indexedExtent returns the bounding box and the Z and M ranges of all
+// shapes in the SHP file read from shp, which are found using the index read
+// from shx.
+func indexedExtent(shp, shx io.ReadSeeker, num int) (extent, error) {
+	var ext extent
+	for row := 0; row < num; row++ {
+		content, err := readRecord(shp, shx, row)
+		if err != nil {
+			return extent{}, err
+		}
+		shape, err := recordShape(content)
+		if err != nil {
+			return extent{}, fmt.Errorf("Error when reading shape %d: %w", row, err)
+		}
+		ext.add(shape)
+	}
+	return ext, nil
+}
@@ -0,0 +1,202 @@
+package shp
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestOpenForUpdate(t *testing.T) {
+	filename := filenamePrefix + "update"
+	defer removeShapefile(filename)
+
+	line := func(points ...Point) *PolyLine {
+		return NewPolyLine([][]Point{points})
+	}
+	w, err := Create(filename+".shp", POLYLINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFields([]Field{StringField("NAME", 10)}); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"first", "second", "third"} {
+		f := float64(i)
+		n := int(w.Write(line(Point{f, f}, Point{f + 1, f + 1}, Point{f + 2, f})))
+		w.WriteAttribute(n, 0, name)
+	}
+	w.Close()
+
+	w, err = OpenForUpdate(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.UpdateAttribute(1, 0, "changed"); err != nil {
+		t.Fatal(err)
+	}
+	// shorter than the old value
+	if err := w.UpdateAttribute(0, 0, "ab"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.UpdateAttribute(3, 0, "invalid"); err == nil {
+		t.Error("expected error when updating row out of range")
+	}
+	if err := w.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	// same length as the old record
+	if err := w.ReplaceShape(0, line(Point{-5, -5}, Point{0, 0}, Point{1, 0})); err != nil {
+		t.Fatal(err)
+	}
+	// needs to be relocated
+	if err := w.ReplaceShape(1, line(Point{1, 1}, Point{2, 2}, Point{3, 3}, Point{10, 10})); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ReplaceShape(3, line(Point{0, 0}, Point{1, 1})); err == nil {
+		t.Error("expected error when replacing shape out of range")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Box{-5, -5, 10, 10}); r.BBox() != want {
+		t.Errorf("got bounding box %v, want %v", r.BBox(), want)
+	}
+	var lengths []int
+	for r.Next() {
+		_, shape := r.Shape()
+		switch s := shape.(type) {
+		case *PolyLine:
+			lengths = append(lengths, len(s.Points))
+		case *Null:
+			lengths = append(lengths, 0)
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{3, 0, 3, 4}
+	if len(lengths) != len(want) {
+		t.Fatalf("got shapes with %v points, want %v", lengths, want)
+	}
+	for i := range want {
+		if lengths[i] != want[i] {
+			t.Fatalf("got shapes with %v points, want %v", lengths, want)
+		}
+	}
+	if got := r.ReadAttribute(0, 0); got != "ab" {
+		t.Errorf("got attribute %q, want %q", got, "ab")
+	}
+	if got := r.ReadAttribute(1, 0); got != "changed" {
+		t.Errorf("got attribute %q, want %q", got, "changed")
+	}
+	if deleted, err := r.IsDeleted(2); !deleted || err != nil {
+		t.Errorf("row 2 is not deleted: %v", err)
+	}
+	r.Close()
+
+	if err := Pack(filename + ".shp"); err != nil {
+		t.Fatal(err)
+	}
+	r, err = Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if want := (Box{-5, -5, 10, 10}); r.BBox() != want {
+		t.Errorf("got bounding box %v after packing, want %v", r.BBox(), want)
+	}
+	lengths = nil
+	for r.Next() {
+		_, shape := r.Shape()
+		lengths = append(lengths, len(shape.(*PolyLine).Points))
+	}
+	if len(lengths) != 2 || lengths[0] != 3 || lengths[1] != 4 {
+		t.Errorf("got shapes with %v points after packing, want [3 4]", lengths)
+	}
+	if got := r.ReadAttribute(1, 0); got != "changed" {
+		t.Errorf("got attribute %q after packing, want %q", got, "changed")
+	}
+}
+
+func TestCloseRecalcError(t *testing.T) {
+	filename := filenamePrefix + "recalc_error"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	w.Write(&Point{2, 2})
+	w.Close()
+
+	w, err = OpenForUpdate(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ReplaceShape(0, &Point{0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	// cut off the second record, so that the bounding box cannot be
+	// recalculated
+	if err := os.Truncate(filename+".shp", 100+28+10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrTruncated) {
+		t.Errorf("got error %v, want ErrTruncated", err)
+	}
+}
+
+func TestReplaceShapeWithoutMeasures(t *testing.T) {
+	filename := filenamePrefix + "replace_measures"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POLYLINEZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(NewPolyLineZ([][]PointZ{{{0, 0, 1, 5}, {1, 1, 2, 6}}}))
+	w.Close()
+
+	w, err = OpenForUpdate(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the new record is shorter because it has no M block
+	if err := w.ReplaceShape(0, NewPolyLineZ([][]PointZ{{{0, 0, 1, NoData}, {2, 2, 3, NoData}}})); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if m := r.MRange(); m != [2]float64{} {
+		t.Errorf("got M range %v, want [0 0]", m)
+	}
+	if !r.Next() {
+		t.Fatalf("could not read shape: %v", r.Err())
+	}
+	if _, s := r.Shape(); s.ShapeType() != NULL {
+		t.Errorf("got %T at the old position, want null shape", s)
+	}
+	if !r.Next() {
+		t.Fatalf("could not read relocated shape: %v", r.Err())
+	}
+	_, s := r.Shape()
+	p := s.(*PolyLineZ)
+	if p.HasM() || !reflect.DeepEqual(p.ZArray, []float64{1, 3}) {
+		t.Errorf("got Z values %v and measures %v", p.ZArray, p.MArray)
+	}
+	if r.Next() || r.Err() != nil {
+		t.Errorf("unexpected end of file: %v", r.Err())
+	}
+}
@@ -8,8 +8,9 @@ import (
 	"math"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
 )
 
 // Writer is the type that is used to write a new shapefile.
@@ -19,12 +20,14 @@ type Writer struct {
 	shx          writeSeekCloser
 	GeometryType ShapeType
 	num          int32
-	bbox         Box
-
-	dbf             writeSeekCloser
-	dbfFields       []Field
-	dbfHeaderLength int16
-	dbfRecordLength int16
+	extent       // bounding box and ranges of Z values and measures
+	// recalcBBox is set if shapes have been replaced, which requires the
+	// bounding box to be calculated from all shapes when closing.
+	recalcBBox bool
+	// err is the first error of Write, which is returned by Close.
+	err error
+
+	dbf *dbf.Writer
 }
 
 type writeSeekCloser interface {
@@ -79,19 +82,21 @@ func Append(filename string) (*Writer, error) {
 	}
 	_, err = shp.Seek(32, io.SeekStart)
 	if err != nil {
-		return nil, fmt.Errorf("cannot seek to SHP geometry type: %v", err)
+		return nil, fmt.Errorf("cannot seek to SHP geometry type: %w", err)
 	}
 	err = binary.Read(shp, binary.LittleEndian, &w.GeometryType)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read geometry type: %v", err)
+		return nil, fmt.Errorf("cannot read geometry type: %w", &FormatError{File: "shp", Offset: 0, Err: truncated(err)})
 	}
 	er := &errReader{Reader: shp}
 	w.bbox.MinX = readFloat64(er)
 	w.bbox.MinY = readFloat64(er)
 	w.bbox.MaxX = readFloat64(er)
 	w.bbox.MaxY = readFloat64(er)
+	binary.Read(er, binary.LittleEndian, &w.zRange)
+	binary.Read(er, binary.LittleEndian, &w.mRange)
 	if er.e != nil {
-		return nil, fmt.Errorf("cannot read bounding box: %v", er.e)
+		return nil, fmt.Errorf("cannot read bounding box: %w", &FormatError{File: "shp", Offset: 0, Err: truncated(er.e)})
 	}
 
 	shx, err := os.OpenFile(basename+".shx", os.O_RDWR, 0666)
@@ -100,72 +105,48 @@ func Append(filename string) (*Writer, error) {
 		// read through all the shapes and create it on the fly
 	}
 	if err != nil {
-		return nil, fmt.Errorf("cannot open shapefile index: %v", err)
+		return nil, fmt.Errorf("cannot open shapefile index: %w", err)
 	}
-	_, err = shx.Seek(-8, io.SeekEnd)
+	last, err := shx.Seek(-8, io.SeekEnd)
 	if err != nil {
-		return nil, fmt.Errorf("cannot seek to last shape index: %v", err)
+		return nil, fmt.Errorf("cannot seek to last shape index: %w", &FormatError{File: "shx", Offset: 0, Err: ErrTruncated})
 	}
 	var offset int32
 	err = binary.Read(shx, binary.BigEndian, &offset)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read last shape index: %v", err)
+		return nil, fmt.Errorf("cannot read last shape index: %w", &FormatError{File: "shx", Offset: last, Err: truncated(err)})
 	}
 	offset = offset * 2
 	_, err = shp.Seek(int64(offset), io.SeekStart)
 	if err != nil {
-		return nil, fmt.Errorf("cannot seek to last shape: %v", err)
+		return nil, fmt.Errorf("cannot seek to last shape: %w", err)
 	}
 	err = binary.Read(shp, binary.BigEndian, &w.num)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read number of last shape: %v", err)
+		return nil, fmt.Errorf("cannot read number of last shape: %w", &FormatError{File: "shp", Offset: int64(offset), Err: truncated(err)})
 	}
+	w.hasBBox = w.num > 0
+	// the ranges of the header hold the existing shapes, even if they are
+	// [0, 0]
+	w.hasZ = w.hasBBox && hasZValues(w.GeometryType)
+	w.hasM = w.hasBBox && hasMeasureValues(w.GeometryType)
 	_, err = shp.Seek(0, io.SeekEnd)
 	if err != nil {
-		return nil, fmt.Errorf("cannot seek to SHP end: %v", err)
+		return nil, fmt.Errorf("cannot seek to SHP end: %w", err)
 	}
 	_, err = shx.Seek(0, io.SeekEnd)
 	if err != nil {
-		return nil, fmt.Errorf("cannot seek to SHX end: %v", err)
+		return nil, fmt.Errorf("cannot seek to SHX end: %w", err)
 	}
 	w.shx = shx
 
-	dbf, err := os.Open(basename + ".dbf")
+	w.dbf, err = dbf.Append(basename + ".dbf")
 	if os.IsNotExist(err) {
 		return w, nil // it's okay if the DBF does not exist
 	}
 	if err != nil {
-		return nil, fmt.Errorf("cannot open DBF: %v", err)
-	}
-
-	_, err = dbf.Seek(8, io.SeekStart)
-	if err != nil {
-		return nil, fmt.Errorf("cannot seek in DBF: %v", err)
-	}
-	err = binary.Read(dbf, binary.LittleEndian, &w.dbfHeaderLength)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read header length from DBF: %v", err)
-	}
-	err = binary.Read(dbf, binary.LittleEndian, &w.dbfRecordLength)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read record length from DBF: %v", err)
-	}
-
-	_, err = dbf.Seek(20, io.SeekCurrent) // skip padding
-	if err != nil {
-		return nil, fmt.Errorf("cannot seek in DBF: %v", err)
-	}
-	numFields := int(math.Floor(float64(w.dbfHeaderLength-33) / 32.0))
-	w.dbfFields = make([]Field, numFields)
-	err = binary.Read(dbf, binary.LittleEndian, &w.dbfFields)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read number of fields from DBF: %v", err)
-	}
-	_, err = dbf.Seek(0, io.SeekEnd) // skip padding
-	if err != nil {
-		return nil, fmt.Errorf("cannot seek to DBF end: %v", err)
+		return nil, fmt.Errorf("cannot open DBF: %w", err)
 	}
-	w.dbf = dbf
 
 	return w, nil
 }
@@ -174,19 +155,42 @@ func Append(filename string) (*Writer, error) {
 // a record in the SHX file and DBF file (if it is
 // initialized). Returns the index of the written object
 // which can be used in WriteAttribute.
+//
+// If the type of shape is neither NULL nor the
+// GeometryType, nothing is written and -1 is returned.
+// The first such error is returned by Close; use
+// WriteShape to get it right away.
 func (w *Writer) Write(shape Shape) int32 {
-	// increate bbox
-	if w.num == 0 {
-		w.bbox = shape.BBox()
-	} else {
-		w.bbox.Extend(shape.BBox())
+	n, err := w.WriteShape(shape)
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return -1
+	}
+	return n
+}
+
+// WriteShape is like Write, but returns an error if the type of shape is
+// neither NULL nor the GeometryType of the shapefile. Null shapes, which
+// may also be given as nil, are written as records of type NULL without
+// content and do not affect the bounding box. The counts, bounding box and
+// ranges of the record are derived from the coordinates of the shape as by
+// its Normalize method, without changing shape itself.
+func (w *Writer) WriteShape(shape Shape) (int32, error) {
+	shape, t, err := w.checkShape(shape)
+	if err != nil {
+		return -1, err
 	}
 
+	// increase bbox and Z and M ranges
+	w.extent.add(shape)
+
 	w.num++
 	binary.Write(w.shp, binary.BigEndian, w.num)
 	w.shp.Seek(4, io.SeekCurrent)
 	start, _ := w.shp.Seek(0, io.SeekCurrent)
-	binary.Write(w.shp, binary.LittleEndian, w.GeometryType)
+	binary.Write(w.shp, binary.LittleEndian, t)
 	shape.write(w.shp)
 	finish, _ := w.shp.Seek(0, io.SeekCurrent)
 	length := int32(math.Floor((float64(finish) - float64(start)) / 2.0))
@@ -200,26 +204,70 @@ func (w *Writer) Write(shape Shape) int32 {
 
 	// write empty record to dbf
 	if w.dbf != nil {
-		w.writeEmptyRecord()
+		w.dbf.AddRecord()
 	}
 
-	return w.num - 1
+	return w.num - 1, nil
+}
+
+// checkShape returns a normalized copy of shape and its type, which must be
+// NULL or the GeometryType of the shapefile. A nil shape is returned as Null.
+func (w *Writer) checkShape(shape Shape) (Shape, ShapeType, error) {
+	if shape == nil {
+		shape = &Null{}
+	}
+	t := shape.ShapeType()
+	if t != NULL && t != w.GeometryType {
+		return nil, t, fmt.Errorf("%w: cannot write %v to %v shapefile", ErrShapeTypeMismatch, t, w.GeometryType)
+	}
+	return normalized(shape), t, nil
 }
 
 // Close closes the Writer. This must be used at the end of
 // the transaction because it writes the correct headers
-// to the SHP/SHX and DBF files before closing.
-func (w *Writer) Close() {
+// to the SHP/SHX and DBF files before closing. If shapes
+// have been replaced, the bounding box and ranges are
+// recalculated from all shapes; if that fails, the files
+// are still closed, but the error is returned. If Write
+// failed before, its first error is returned.
+//
+// Close used to have no result. Deferred calls and calls
+// whose result is ignored still compile, but code that
+// assigns Close to a func() or requires an interface with
+// Close() must be changed to the new signature.
+func (w *Writer) Close() error {
+	err := w.err
+	if w.recalcBBox {
+		shp, ok1 := w.shp.(io.ReadSeeker)
+		shx, ok2 := w.shx.(io.ReadSeeker)
+		if ok1 && ok2 {
+			if ext, rerr := indexedExtent(shp, shx, int(w.num)); rerr == nil {
+				w.extent = ext
+			} else if err == nil {
+				err = fmt.Errorf("cannot recalculate bounding box: %w", rerr)
+			}
+		}
+	}
 	w.writeHeader(w.shx)
 	w.writeHeader(w.shp)
-	w.shp.Close()
-	w.shx.Close()
+	if cerr := w.shp.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := w.shx.Close(); err == nil {
+		err = cerr
+	}
 
 	if w.dbf == nil {
-		w.SetFields([]Field{})
+		if serr := w.SetFields([]Field{}); err == nil {
+			err = serr
+		}
+	}
+	if w.dbf != nil {
+		if cerr := w.dbf.Close(); err == nil {
+			err = cerr
+		}
 	}
-	w.writeDbfHeader(w.dbf)
-	w.dbf.Close()
+	return err
 }
 
 // writeHeader wrires SHP/SHX headers to ws.
@@ -238,105 +286,68 @@ func (w *Writer) writeHeader(ws io.WriteSeeker) {
 	// bounding box
 	binary.Write(ws, binary.LittleEndian, w.bbox)
 	// elevation, measure
-	binary.Write(ws, binary.LittleEndian, []float64{0.0, 0.0, 0.0, 0.0})
-}
-
-// writeDbfHeader writes a DBF header to ws.
-func (w *Writer) writeDbfHeader(ws io.WriteSeeker) {
-	ws.Seek(0, 0)
-	// version, year (YEAR-1990), month, day
-	binary.Write(ws, binary.LittleEndian, []byte{3, 24, 5, 3})
-	// number of records
-	binary.Write(ws, binary.LittleEndian, w.num)
-	// header length, record length
-	binary.Write(ws, binary.LittleEndian, []int16{w.dbfHeaderLength, w.dbfRecordLength})
-	// padding
-	binary.Write(ws, binary.LittleEndian, make([]byte, 20))
-
-	for _, field := range w.dbfFields {
-		binary.Write(ws, binary.LittleEndian, field)
-	}
-
-	// end with return
-	ws.Write([]byte("\r"))
+	binary.Write(ws, binary.LittleEndian, w.zRange)
+	binary.Write(ws, binary.LittleEndian, w.mRange)
 }
 
 // SetFields sets field values in the DBF. This initializes the DBF file and
-// should be used prior to writing any attributes.
-func (w *Writer) SetFields(fields []Field) error {
+// should be used prior to writing any attributes. The names of the fields
+// must be unique and consist of at most 10 ASCII characters, otherwise an
+// error is returned. Use SetNamedFields to derive valid names from
+// arbitrary ones. The header of the DBF file can be configured with opts,
+// e.g. to set the date of the last update with dbf.Date.
+func (w *Writer) SetFields(fields []Field, opts ...dbf.Option) error {
 	if w.dbf != nil {
 		return errors.New("Cannot set fields in existing dbf")
 	}
 
 	var err error
-	w.dbf, err = os.Create(w.filename + ".dbf")
+	w.dbf, err = dbf.Create(w.filename+".dbf", fields, opts...)
 	if err != nil {
-		return fmt.Errorf("Failed to open %s.dbf: %v", w.filename, err)
+		return fmt.Errorf("Failed to open %s.dbf: %w", w.filename, err)
 	}
-	w.dbfFields = fields
-
-	// calculate record length
-	w.dbfRecordLength = int16(1)
-	for _, field := range w.dbfFields {
-		w.dbfRecordLength += int16(field.Size)
-	}
-
-	// header lengh
-	w.dbfHeaderLength = int16(len(w.dbfFields)*32 + 33)
-
-	// fill header space with empty bytes for now
-	buf := make([]byte, w.dbfHeaderLength)
-	binary.Write(w.dbf, binary.LittleEndian, buf)
 
 	// write empty records
 	for n := int32(0); n < w.num; n++ {
-		w.writeEmptyRecord()
+		w.dbf.AddRecord()
 	}
 	return nil
 }
 
-// Writes an empty record to the end of the DBF. This
-// works by seeking to the end of the file and writing
-// dbfRecordLength number of bytes. The first byte is a
-// space that indicates a new record.
-func (w *Writer) writeEmptyRecord() {
-	w.dbf.Seek(0, io.SeekEnd)
-	buf := make([]byte, w.dbfRecordLength)
-	buf[0] = ' '
-	binary.Write(w.dbf, binary.LittleEndian, buf)
+// SetNamedFields is like SetFields, but names the fields after names, which
+// are turned into valid and unique field names with dbf.UniqueNames first.
+// It returns a map from the requested names to the names that were written.
+func (w *Writer) SetNamedFields(names []string, fields []Field, opts ...dbf.Option) (map[string]string, error) {
+	if len(names) != len(fields) {
+		return nil, fmt.Errorf("Got %d names for %d fields", len(names), len(fields))
+	}
+	written := make(map[string]string)
+	named := make([]Field, len(fields))
+	for i, name := range dbf.UniqueNames(names) {
+		if _, ok := written[names[i]]; ok {
+			return nil, fmt.Errorf("Duplicate field name %s", names[i])
+		}
+		written[names[i]] = name
+		named[i] = fields[i]
+		named[i].Name = [11]byte{}
+		copy(named[i].Name[:], name)
+	}
+	if err := w.SetFields(named, opts...); err != nil {
+		return nil, err
+	}
+	return written, nil
 }
 
 // WriteAttribute writes value for field into the given row in the DBF. Row
 // number should be the same as the order the Shape was written to the
 // Shapefile. The field value corresponds to the field in the slice used in
-// SetFields.
+// SetFields. The value can be an int, int64, float64, string, bool or
+// time.Time, as long as it suits the type of the field.
 func (w *Writer) WriteAttribute(row int, field int, value interface{}) error {
-	var buf []byte
-	switch v := value.(type) {
-	case int:
-		buf = []byte(strconv.Itoa(v))
-	case float64:
-		precision := w.dbfFields[field].Precision
-		buf = []byte(strconv.FormatFloat(v, 'f', int(precision), 64))
-	case string:
-		buf = []byte(v)
-	default:
-		return fmt.Errorf("Unsupported value type: %T", v)
-	}
-
 	if w.dbf == nil {
-		return errors.New("Initialize DBF by using SetFields first")
-	}
-	if sz := int(w.dbfFields[field].Size); len(buf) > sz {
-		return fmt.Errorf("Unable to write field %v: %q exceeds field length %v", field, buf, sz)
-	}
-
-	seekTo := 1 + int64(w.dbfHeaderLength) + (int64(row) * int64(w.dbfRecordLength))
-	for n := 0; n < field; n++ {
-		seekTo += int64(w.dbfFields[n].Size)
+		return fmt.Errorf("%w: initialize it by using SetFields first", ErrNoDBF)
 	}
-	w.dbf.Seek(seekTo, io.SeekStart)
-	return binary.Write(w.dbf, binary.LittleEndian, buf)
+	return w.dbf.WriteAttribute(row, field, value)
 }
 
 // BBox returns the bounding box of the Writer.
@@ -1,11 +1,16 @@
 package shp
 
 import (
-	"bytes"
-	"io"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jonas-p/go-shp/dbf"
 )
 
 var filenamePrefix = "test_files/write_"
@@ -14,6 +19,8 @@ func removeShapefile(filename string) {
 	os.Remove(filename + ".shp")
 	os.Remove(filename + ".shx")
 	os.Remove(filename + ".dbf")
+	os.Remove(filename + ".dbt")
+	os.Remove(filename + ".fpt")
 }
 
 func pointsToFloats(points []Point) [][]float64 {
@@ -141,69 +148,360 @@ func TestWritePolyLine(t *testing.T) {
 	testPolyLine(t, pointsToFloats(flatten(points)), shapes)
 }
 
-type seekTracker struct {
-	io.Writer
-	offset int64
+func TestWriteAttributes(t *testing.T) {
+	filename := filenamePrefix + "attributes"
+	defer removeShapefile(filename)
+
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []Field{
+		StringField("NAME", 10),
+		NumberField("COUNT", 5),
+		FloatField("VALUE", 8, 2),
+	}
+	if err := shape.SetFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		n := int(shape.Write(&Point{float64(i), float64(i)}))
+		shape.WriteAttribute(n, 0, fmt.Sprintf("point %d", i))
+		shape.WriteAttribute(n, 1, i*10)
+	}
+	if err := shape.WriteAttribute(1, 2, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	shape.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.AttributeCount(); got != 3 {
+		t.Fatalf("got %d attribute rows, want 3", got)
+	}
+	want := [][]string{
+		{"point 0", "0", ""},
+		{"point 1", "10", "1.50"},
+		{"point 2", "20", ""},
+	}
+	for row := range want {
+		for field := range fields {
+			if got := r.ReadAttribute(row, field); got != want[row][field] {
+				t.Errorf("row %d, field %d: got %q, want %q", row, field, got, want[row][field])
+			}
+		}
+	}
 }
 
-func (s *seekTracker) Seek(offset int64, whence int) (int64, error) {
-	s.offset = offset
-	return s.offset, nil
+func TestWriteTypedAttributes(t *testing.T) {
+	filename := filenamePrefix + "typed"
+	defer removeShapefile(filename)
+
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape.SetFields([]Field{
+		LogicalField("FLAG"),
+		IntegerField("COUNT"),
+		DateTimeField("SEEN"),
+	})
+	seen := time.Date(2018, 7, 14, 13, 45, 30, 0, time.UTC)
+	n := int(shape.Write(&Point{1, 1}))
+	for field, v := range []interface{}{true, int64(7), seen} {
+		if err := shape.WriteAttribute(n, field, v); err != nil {
+			t.Fatalf("field %d: %v", field, err)
+		}
+	}
+	shape.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for field, want := range []string{"T", "7", "20180714134530"} {
+		if got := r.ReadAttribute(0, field); got != want {
+			t.Errorf("field %d: got %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestWriteMemo(t *testing.T) {
+	filename := filenamePrefix + "memo"
+	defer removeShapefile(filename)
+
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape.SetFields([]Field{
+		StringField("NAME", 10),
+		MemoField("NOTES"),
+	})
+	long := strings.Repeat("A rather long note. ", 100)
+	notes := []string{"short", "", long}
+	for i, note := range notes {
+		n := int(shape.Write(&Point{float64(i), float64(i)}))
+		if err := shape.WriteAttribute(n, 1, note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shape.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for row, want := range notes {
+		if got := r.ReadAttribute(row, 1); got != want {
+			t.Errorf("row %d: got %q, want %q", row, got, want)
+		}
+	}
 }
 
-func (s *seekTracker) Close() error {
-	return nil
+func TestWriteFoxProMemo(t *testing.T) {
+	filename := filenamePrefix + "foxpro_memo"
+	defer removeShapefile(filename)
+
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shape.SetFields([]Field{MemoField("NOTES")}, dbf.Version(dbf.VisualFoxPro)); err != nil {
+		t.Fatal(err)
+	}
+	n := int(shape.Write(&Point{1, 1}))
+	if err := shape.WriteAttribute(n, 0, "note"); err != nil {
+		t.Fatal(err)
+	}
+	if err := shape.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// a stray dBase memo file must not be used for a FoxPro table
+	if err := ioutil.WriteFile(filename+".dbt", make([]byte, 512), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.ReadAttribute(0, 0); got != "note" {
+		t.Errorf("got %q, want %q", got, "note")
+	}
 }
 
-func TestWriteAttribute(t *testing.T) {
-	buf := new(bytes.Buffer)
-	s := &seekTracker{Writer: buf}
-	w := Writer{
-		dbf: s,
-		dbfFields: []Field{
-			StringField("A_STRING", 6),
-			FloatField("A_FLOAT", 8, 4),
-			NumberField("AN_INT", 4),
-		},
-		dbfRecordLength: 100,
-	}
-
-	tests := []struct {
-		name       string
-		row        int
-		field      int
-		data       interface{}
-		wantOffset int64
-		wantData   string
-	}{
-		{"string-0", 0, 0, "test", 1, "test"},
-		{"string-0-overflow-1", 0, 0, "overflo", 0, ""},
-		{"string-0-overflow-n", 0, 0, "overflowing", 0, ""},
-		{"string-3", 3, 0, "things", 301, "things"},
-		{"float-0", 0, 1, 123.44, 7, "123.4400"},
-		{"float-0-overflow-1", 0, 1, 1234.0, 0, ""},
-		{"float-0-overflow-n", 0, 1, 123456789.0, 0, ""},
-		{"int-0", 0, 2, 4242, 15, "4242"},
-		{"int-0-overflow-1", 0, 2, 42424, 0, ""},
-		{"int-0-overflow-n", 0, 2, 42424343, 0, ""},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			buf.Reset()
-			s.offset = 0
-
-			err := w.WriteAttribute(test.row, test.field, test.data)
-
-			if buf.String() != test.wantData {
-				t.Errorf("got data: %v, want: %v", buf.String(), test.wantData)
-			}
-			if s.offset != test.wantOffset {
-				t.Errorf("got seek offset: %v, want: %v", s.offset, test.wantOffset)
-			}
-			if err == nil && test.wantData == "" {
-				t.Error("got no data and no error")
-			}
-		})
+func TestSetNamedFields(t *testing.T) {
+	filename := filenamePrefix + "names"
+	defer removeShapefile(filename)
+
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shape.SetFields([]Field{NumberField("POPULATION_2020", 5)}); err == nil {
+		t.Error("expected error for field name longer than 10 characters")
+	}
+	names := []string{"POPULATION_2020", "POPULATION_2021"}
+	written, err := shape.SetNamedFields(names, []Field{NumberField("", 8), NumberField("", 8)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written["POPULATION_2020"] != "POPULATION" || written["POPULATION_2021"] != "POPULATI_1" {
+		t.Errorf("got names %v", written)
+	}
+	n := int(shape.Write(&Point{1, 1}))
+	shape.WriteAttribute(n, 1, 2021)
+	shape.Close()
+
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	field, ok := r.FieldIndex(written["POPULATION_2021"])
+	if !ok || field != 1 {
+		t.Fatalf("got field index %d, %v, want 1", field, ok)
+	}
+	if got := r.ReadAttribute(0, field); got != "2021" {
+		t.Errorf("got %q, want 2021", got)
+	}
+	if _, ok := r.FieldIndex("POPULATION_2021"); ok {
+		t.Error("found field under requested name")
+	}
+}
+
+func TestWriteDBFHeader(t *testing.T) {
+	filename := filenamePrefix + "header"
+	defer removeShapefile(filename)
+
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)
+	if err := shape.SetFields([]Field{StringField("NAME", 5)}, dbf.Date(date)); err != nil {
+		t.Fatal(err)
+	}
+	shape.Write(&Point{1, 1})
+	shape.Close()
+
+	sr := SequentialReaderFromExt(openFile(filename+".shp", t), openFile(filename+".dbf", t))
+	defer sr.Close()
+	h, ok := DBFHeader(sr)
+	if !ok {
+		t.Fatal("no DBF header")
+	}
+	if h.Version != dbf.DBase3 || !h.LastUpdate.Equal(date) {
+		t.Errorf("got version %#x and date %v", h.Version, h.LastUpdate)
+	}
+}
+
+func TestWriteShapeType(t *testing.T) {
+	filename := filenamePrefix + "shapetype"
+	defer removeShapefile(filename)
+	shape, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shape.SetFields([]Field{StringField("NAME", 5)}); err != nil {
+		t.Fatal(err)
+	}
+	line := NewPolyLine([][]Point{{{0, 0}, {1, 1}}})
+	if _, err := shape.WriteShape(line); !errors.Is(err, ErrShapeTypeMismatch) {
+		t.Errorf("got error %v when writing polyline to point shapefile, want ErrShapeTypeMismatch", err)
+	}
+	if n := shape.Write(line); n != -1 {
+		t.Errorf("wrote polyline to point shapefile as shape %d", n)
+	}
+	if err := shape.WriteAttribute(-1, 0, "BAD"); err == nil {
+		t.Error("wrote attribute of rejected shape")
+	}
+	for _, s := range []Shape{&Point{5, 5}, &Null{}, nil, &Point{10, 8}} {
+		if _, err := shape.WriteShape(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := (Box{5, 5, 10, 8}); shape.BBox() != want {
+		t.Errorf("got bounding box %v, want %v without null shapes", shape.BBox(), want)
+	}
+	if err := shape.Close(); !errors.Is(err, ErrShapeTypeMismatch) {
+		t.Errorf("got error %v when closing, want ErrShapeTypeMismatch from Write", err)
+	}
+
+	r, err := Open(filename+".shp", EnforceShapeType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var types []ShapeType
+	for r.Next() {
+		_, s := r.Shape()
+		types = append(types, s.ShapeType())
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	if want := []ShapeType{POINT, NULL, NULL, POINT}; !reflect.DeepEqual(types, want) {
+		t.Errorf("read shapes of type %v, want %v", types, want)
+	}
+}
+
+func TestWriteZMRange(t *testing.T) {
+	filename := filenamePrefix + "zmrange"
+	defer removeShapefile(filename)
+	shape, err := Create(filename+".shp", POINTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape.Write(&PointZ{1, 2, 3, 4})
+	shape.Write(&Null{})
+	shape.Write(&PointZ{5, 6, -1, -1e39}) // measure without data
+	shape.Close()
+
+	wantZ, wantM := [2]float64{-1, 3}, [2]float64{4, 4}
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ZRange() != wantZ || r.MRange() != wantM {
+		t.Errorf("got Z range %v and M range %v, want %v and %v", r.ZRange(), r.MRange(), wantZ, wantM)
+	}
+	r.Close()
+
+	shp, err := os.Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := SequentialReaderFromExt(shp, nil)
+	defer sr.Close()
+	if ZRange(sr) != wantZ || MRange(sr) != wantM {
+		t.Errorf("sequential: got Z range %v and M range %v, want %v and %v", ZRange(sr), MRange(sr), wantZ, wantM)
+	}
+
+	// appending extends the ranges from the header
+	shape, err = Append(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape.Write(&PointZ{0, 0, 10, 2})
+	shape.Close()
+	r, err = Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if wantZ, wantM := [2]float64{-1, 10}, [2]float64{2, 4}; r.ZRange() != wantZ || r.MRange() != wantM {
+		t.Errorf("after append: got Z range %v and M range %v, want %v and %v", r.ZRange(), r.MRange(), wantZ, wantM)
+	}
+}
+
+func TestAppendZeroRange(t *testing.T) {
+	filename := filenamePrefix + "zerorange"
+	defer removeShapefile(filename)
+	shape, err := Create(filename+".shp", POINTZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape.Write(&PointZ{1, 2, 0, 0})
+	shape.Close()
+
+	// the ranges [0, 0] of the header must not be dropped
+	shape, err = Append(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape.Write(&PointZ{0, 0, 5, 7})
+	shape.Close()
+	r, err := Open(filename + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if wantZ, wantM := [2]float64{0, 5}, [2]float64{0, 7}; r.ZRange() != wantZ || r.MRange() != wantM {
+		t.Errorf("got Z range %v and M range %v, want %v and %v", r.ZRange(), r.MRange(), wantZ, wantM)
+	}
+}
+
+func TestCloseWithoutDBF(t *testing.T) {
+	filename := filenamePrefix + "close_without_dbf"
+	defer removeShapefile(filename)
+	w, err := Create(filename+".shp", POINT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&Point{1, 1})
+	// the empty DBF file cannot be created in place of a directory
+	if err := os.Mkdir(filename+".dbf", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err == nil {
+		t.Error("expected error when the DBF file cannot be created")
 	}
 }
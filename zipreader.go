@@ -4,79 +4,159 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
-	"path"
+	"os"
 	"strings"
+
+	"github.com/jonas-p/go-shp/dbf"
 )
 
 // ZipReader provides an interface for reading Shapefiles that are compressed in a ZIP archive.
 type ZipReader struct {
 	sr SequentialReader
-	z  *zip.ReadCloser
+	z  io.Closer // nil if the archive is not owned by the ZipReader
 }
 
-// openFromZIP is convenience function for opening the file called name that is
-// compressed in z for reading.
-func openFromZIP(z *zip.ReadCloser, name string) (io.ReadCloser, error) {
-	for _, f := range z.File {
-		if f.Name == name {
-			return f.Open()
+// ZipLayer describes a shapefile in a ZIP archive together with the files
+// that belong to it.
+type ZipLayer struct {
+	// Name is the path of the .shp file in the archive.
+	Name string
+	// Files maps the lower-case extensions (e.g. ".dbf") of all files of the
+	// shapefile that were found in the archive to their path and size.
+	Files map[string]ZipLayerFile
+}
 
-		}
+// ZipLayerFile is a file that belongs to a ZipLayer.
+type ZipLayerFile struct {
+	// Name is the path of the file in the archive.
+	Name string
+	// Size is the uncompressed size of the file in bytes.
+	Size int64
+}
+
+// Has reports whether the file with extension ext, e.g. ".prj", belongs to
+// the layer. The extension is compared case-insensitively.
+func (l ZipLayer) Has(ext string) bool {
+	_, ok := l.Files[strings.ToLower(ext)]
+	return ok
+}
+
+// zipLayers returns the shapefiles in z.
+func zipLayers(z *zip.Reader) []archiveLayer {
+	names := make([]string, len(z.File))
+	for i, f := range z.File {
+		names[i] = f.Name
+	}
+	return findLayers(names)
+}
+
+// openFromZIP is convenience function for opening the file of layer l with
+// extension ext that is compressed in z for reading.
+func openFromZIP(z *zip.Reader, l archiveLayer, ext string) (io.ReadCloser, error) {
+	i, ok := l.files[ext]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s file for %s", ErrFileNotFound, ext, l.name)
 	}
-	return nil, fmt.Errorf("No such file in archive: %s", name)
+	return z.File[i].Open()
 }
 
 // OpenZip opens a ZIP file that contains a single shapefile.
-func OpenZip(zipFilePath string) (*ZipReader, error) {
+func OpenZip(zipFilePath string, opts ...ReadOption) (*ZipReader, error) {
 	z, err := zip.OpenReader(zipFilePath)
 	if err != nil {
 		return nil, err
 	}
-	zr := &ZipReader{
-		z: z,
-	}
-	shapeFiles := shapesInZip(z)
-	if len(shapeFiles) == 0 {
-		return nil, fmt.Errorf("archive does not contain a .shp file")
+	zr, err := singleShapeFromZip(&z.Reader, opts)
+	if err != nil {
+		z.Close()
+		return nil, err
 	}
-	if len(shapeFiles) > 1 {
-		return nil, fmt.Errorf("archive does contain multiple .shp files")
+	zr.z = z
+	return zr, nil
+}
+
+// NewZipReader returns a ZipReader for the single shapefile that is contained
+// in the ZIP archive read from r, which is assumed to have the given size in
+// bytes. This allows reading archives that are held in memory, e.g. from a
+// bytes.Reader, without writing them to disk first. Closing the ZipReader does
+// not close r.
+func NewZipReader(r io.ReaderAt, size int64, opts ...ReadOption) (*ZipReader, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
 	}
+	return singleShapeFromZip(z, opts)
+}
 
-	shp, err := openFromZIP(zr.z, shapeFiles[0].Name)
+// singleShapeFromZip opens the only shapefile in z. It fails if z contains no
+// or more than one shapefile.
+func singleShapeFromZip(z *zip.Reader, opts []ReadOption) (*ZipReader, error) {
+	l, err := singleLayer(zipLayers(z))
 	if err != nil {
 		return nil, err
 	}
-	withoutExt := strings.TrimSuffix(shapeFiles[0].Name, ".shp")
-	// dbf is optional, so no error checking here
-	dbf, _ := openFromZIP(zr.z, withoutExt+".dbf")
-	zr.sr = SequentialReaderFromExt(shp, dbf)
-	return zr, nil
+	return shapeFromZip(z, l, opts)
 }
 
 // ShapesInZip returns a string-slice with the names (i.e. relatives paths in
 // archive file tree) of all shapes that are in the ZIP archive at zipFilePath.
+// Hidden files and resource forks, e.g. in a __MACOSX directory, are ignored.
+// Use LayersInZip to learn which other files belong to the shapes.
 func ShapesInZip(zipFilePath string) ([]string, error) {
+	layers, err := LayersInZip(zipFilePath)
+	return layerNames(layers), err
+}
+
+// ShapesInZipReader is like ShapesInZip, but reads the ZIP archive of the
+// given size from r.
+func ShapesInZipReader(r io.ReaderAt, size int64) ([]string, error) {
+	layers, err := LayersInZipReader(r, size)
+	return layerNames(layers), err
+}
+
+func layerNames(layers []ZipLayer) []string {
 	var names []string
+	for _, l := range layers {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// LayersInZip returns all shapefiles in the ZIP archive at zipFilePath
+// together with the files that belong to them. The extensions of the files
+// are matched case-insensitively.
+func LayersInZip(zipFilePath string) ([]ZipLayer, error) {
 	z, err := zip.OpenReader(zipFilePath)
 	if err != nil {
 		return nil, err
 	}
-	shapeFiles := shapesInZip(z)
-	for i := range shapeFiles {
-		names = append(names, shapeFiles[i].Name)
+	defer z.Close()
+	return layersInZip(&z.Reader), nil
+}
+
+// LayersInZipReader is like LayersInZip, but reads the ZIP archive of the
+// given size from r.
+func LayersInZipReader(r io.ReaderAt, size int64) ([]ZipLayer, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
 	}
-	return names, nil
+	return layersInZip(z), nil
 }
 
-func shapesInZip(z *zip.ReadCloser) []*zip.File {
-	var shapeFiles []*zip.File
-	for _, f := range z.File {
-		if strings.HasSuffix(f.Name, ".shp") {
-			shapeFiles = append(shapeFiles, f)
+func layersInZip(z *zip.Reader) []ZipLayer {
+	var layers []ZipLayer
+	for _, l := range zipLayers(z) {
+		zl := ZipLayer{Name: l.name, Files: make(map[string]ZipLayerFile)}
+		for ext, i := range l.files {
+			zl.Files[ext] = ZipLayerFile{
+				Name: z.File[i].Name,
+				Size: int64(z.File[i].UncompressedSize64),
+			}
 		}
+		layers = append(layers, zl)
 	}
-	return shapeFiles
+	return layers
 }
 
 // OpenShapeFromZip opens a shape file that is contained in a ZIP archive. The
@@ -85,41 +165,71 @@ func shapesInZip(z *zip.ReadCloser) []*zip.File {
 // drive letter (e.g. C:) or leading slash, and only forward slashes are
 // allowed. These rules are the same as in
 // https://golang.org/pkg/archive/zip/#FileHeader.
-func OpenShapeFromZip(zipFilePath string, name string) (*ZipReader, error) {
+func OpenShapeFromZip(zipFilePath string, name string, opts ...ReadOption) (*ZipReader, error) {
 	z, err := zip.OpenReader(zipFilePath)
 	if err != nil {
 		return nil, err
 	}
-	zr := &ZipReader{
-		z: z,
+	l, err := layerByName(zipLayers(&z.Reader), name)
+	if err != nil {
+		z.Close()
+		return nil, err
 	}
-
-	shp, err := openFromZIP(zr.z, name)
+	zr, err := shapeFromZip(&z.Reader, l, opts)
 	if err != nil {
+		z.Close()
 		return nil, err
 	}
-	// dbf is optional, so no error checking here
-	prefix := strings.TrimSuffix(name, path.Ext(name))
-	dbf, _ := openFromZIP(zr.z, prefix+".dbf")
-	zr.sr = SequentialReaderFromExt(shp, dbf)
+	zr.z = z
 	return zr, nil
 }
 
-// Close closes the ZipReader and frees the allocated resources.
-func (zr *ZipReader) Close() error {
-	s := ""
-	err := zr.sr.Close()
+// NewZipReaderForShape is like OpenShapeFromZip, but reads the ZIP archive of
+// the given size from r. Closing the ZipReader does not close r.
+func NewZipReaderForShape(r io.ReaderAt, size int64, name string, opts ...ReadOption) (*ZipReader, error) {
+	z, err := zip.NewReader(r, size)
 	if err != nil {
-		s += err.Error() + ". "
+		return nil, err
 	}
-	err = zr.z.Close()
+	l, err := layerByName(zipLayers(z), name)
 	if err != nil {
-		s += err.Error() + ". "
+		return nil, err
 	}
-	if s != "" {
-		return fmt.Errorf(s)
+	return shapeFromZip(z, l, opts)
+}
+
+// shapeFromZip opens the SHP and DBF files of layer l from z.
+func shapeFromZip(z *zip.Reader, l archiveLayer, opts []ReadOption) (*ZipReader, error) {
+	shp, err := openFromZIP(z, l, ".shp")
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	// dbf is optional, so no error checking here
+	dbf, _ := openFromZIP(z, l, ".dbf")
+	sr := &seqReader{shp: shp, readOptions: newReadOptions(opts)}
+	sr.readHeaders(dbf)
+	sr.openMemo(layerOpener(l, func(i int) (readSeekCloser, error) {
+		rc, err := z.File[i].Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return spill(rc, int64(z.File[i].UncompressedSize64))
+	}))
+	return &ZipReader{sr: sr}, nil
+}
+
+// Close closes the ZipReader and frees the allocated resources. The archive
+// is closed even if closing the shapefile fails, in which case that error is
+// returned.
+func (zr *ZipReader) Close() error {
+	err := zr.sr.Close()
+	if zr.z != nil {
+		if cerr := zr.z.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
 // Next reads the next shape in the shapefile and the next row in the DBF. Call
@@ -145,7 +255,105 @@ func (zr *ZipReader) Fields() []Field {
 	return zr.sr.Fields()
 }
 
+// deleted reports whether the record of the shape that was last read is
+// marked as deleted, which makes the ZipReader work with IsDeleted.
+func (zr *ZipReader) deleted() bool {
+	return IsDeleted(zr.sr)
+}
+
+func (zr *ZipReader) dbfHeader() (dbf.Header, bool) {
+	return DBFHeader(zr.sr)
+}
+
+// ZRange returns the minimum and maximum Z value of the shapefile from its
+// header.
+func (zr *ZipReader) ZRange() [2]float64 {
+	return ZRange(zr.sr)
+}
+
+// MRange returns the minimum and maximum measure of the shapefile from its
+// header.
+func (zr *ZipReader) MRange() [2]float64 {
+	return MRange(zr.sr)
+}
+
+// Problems returns the records that were skipped in lenient mode.
+func (zr *ZipReader) Problems() []*FormatError {
+	return Problems(zr.sr)
+}
+
 // Err returns the last non-EOF error that was encountered by this ZipReader.
 func (zr *ZipReader) Err() error {
 	return zr.sr.Err()
 }
+
+// OpenFromZip opens the shapefile called name that is contained in the ZIP
+// archive at zipFilePath for random access. If name is empty, the archive must
+// contain a single shapefile. Files that are stored uncompressed in the
+// archive are read in place, compressed files are inflated into a temporary
+// buffer when they are first accessed.
+func OpenFromZip(zipFilePath string, name string, opts ...ReadOption) (*Reader, error) {
+	f, err := os.Open(zipFilePath)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewReaderFromZip(f, fi.Size(), name, opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.archive = f
+	return r, nil
+}
+
+// NewReaderFromZip is like OpenFromZip, but reads the ZIP archive of the given
+// size from ra. Closing the Reader does not close ra.
+func NewReaderFromZip(ra io.ReaderAt, size int64, name string, opts ...ReadOption) (*Reader, error) {
+	z, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	return readerFromZip(ra, z, name, opts)
+}
+
+// readerFromZip returns a Reader for the shapefile called name in z, which has
+// been opened from ra.
+func readerFromZip(ra io.ReaderAt, z *zip.Reader, name string, opts []ReadOption) (*Reader, error) {
+	layers := zipLayers(z)
+	var l archiveLayer
+	var err error
+	if name == "" {
+		l, err = singleLayer(layers)
+	} else {
+		l, err = layerByName(layers, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readerFromLayer(l, func(i int) (readSeekCloser, error) {
+		return openSeekableFromZIP(ra, z.File[i])
+	}, opts)
+}
+
+// openSeekableFromZIP opens f, which is a member of a ZIP archive that has
+// been opened from ra, for random access.
+func openSeekableFromZIP(ra io.ReaderAt, f *zip.File) (readSeekCloser, error) {
+	if f.Method == zip.Store {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		return nopSeekCloser{io.NewSectionReader(ra, offset, int64(f.UncompressedSize64))}, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return spill(rc, int64(f.UncompressedSize64))
+}
@@ -2,6 +2,7 @@ package shp
 
 import (
 	"archive/zip"
+	"bytes"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -11,12 +12,13 @@ import (
 	"testing"
 )
 
-func compressFileToZIP(zw *zip.Writer, src, tgt string, t *testing.T) {
+func compressFileToZIP(zw *zip.Writer, src, tgt string, method uint16, t *testing.T) {
 	r, err := os.Open(src)
 	if err != nil {
 		t.Fatalf("Could not open for compression %s: %v", src, err)
 	}
-	w, err := zw.Create(tgt)
+	defer r.Close()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: tgt, Method: method})
 	if err != nil {
 		t.Fatalf("Could not start to compress %s: %v", tgt, err)
 	}
@@ -29,6 +31,12 @@ func compressFileToZIP(zw *zip.Writer, src, tgt string, t *testing.T) {
 // createTempZIP packs the SHP, SHX, and DBF into a ZIP in a temporary
 // directory
 func createTempZIP(prefix string, t *testing.T) (dir, filename string) {
+	return createTempZIPWithMethod(prefix, zip.Deflate, t)
+}
+
+// createTempZIPWithMethod is like createTempZIP, but uses the given compression
+// method.
+func createTempZIPWithMethod(prefix string, method uint16, t *testing.T) (dir, filename string) {
 	dir, err := ioutil.TempDir("", "go-shp-test")
 	if err != nil {
 		t.Fatalf("Could not create temporary directory: %v", err)
@@ -41,11 +49,14 @@ func createTempZIP(prefix string, t *testing.T) (dir, filename string) {
 	}
 	zw := zip.NewWriter(w)
 	for _, suffix := range []string{".shp", ".shx", ".dbf"} {
-		compressFileToZIP(zw, prefix+suffix, base+suffix, t)
+		compressFileToZIP(zw, prefix+suffix, base+suffix, method, t)
 	}
 	if err := zw.Close(); err != nil {
 		t.Fatalf("Could not close the written zip: %v", err)
 	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Could not close the zip file: %v", err)
+	}
 	return dir, zipName
 }
 
@@ -77,6 +88,201 @@ func TestZipReader(t *testing.T) {
 	}
 }
 
+func getShapesZippedFromMemory(prefix string, t *testing.T) (shapes []Shape) {
+	dir, filename := createTempZIP(prefix, t)
+	defer os.RemoveAll(dir)
+	b, err := ioutil.ReadFile(filepath.Join(dir, filename))
+	if err != nil {
+		t.Fatalf("Could not read zip file: %v", err)
+	}
+	var sr SequentialReader
+	sr, err = NewZipReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Error when opening zip from memory: %v", err)
+	}
+	for sr.Next() {
+		_, shape := sr.Shape()
+		shapes = append(shapes, shape)
+	}
+	if err := sr.Err(); err != nil {
+		t.Errorf("Error when iterating over the shapes: %v", err)
+	}
+	if err := sr.Close(); err != nil {
+		t.Errorf("Could not close zipreader: %v", err)
+	}
+	return shapes
+}
+
+func TestZipReaderFromMemory(t *testing.T) {
+	for prefix := range dataForReadTests {
+		t.Logf("Testing zipped reading from memory for %s", prefix)
+		testshapeIdentity(t, prefix, getShapesZippedFromMemory)
+	}
+}
+
+func TestShapesInZipReader(t *testing.T) {
+	dir, filename := createTempZIP("test_files/polygon", t)
+	defer os.RemoveAll(dir)
+	b, err := ioutil.ReadFile(filepath.Join(dir, filename))
+	if err != nil {
+		t.Fatalf("Could not read zip file: %v", err)
+	}
+	names, err := ShapesInZipReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "polygon.shp" {
+		t.Fatalf("got shapes %v, want [polygon.shp]", names)
+	}
+	zr, err := NewZipReaderForShape(bytes.NewReader(b), int64(len(b)), names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if !zr.Next() {
+		t.Fatalf("could not read shape: %v", zr.Err())
+	}
+	if _, s := zr.Shape(); s == nil {
+		t.Fatal("got nil shape")
+	}
+}
+
+func TestOpenFromZip(t *testing.T) {
+	for _, method := range []uint16{zip.Store, zip.Deflate} {
+		for prefix := range dataForReadTests {
+			getter := func(prefix string, t *testing.T) []Shape {
+				dir, filename := createTempZIPWithMethod(prefix, method, t)
+				defer os.RemoveAll(dir)
+				r, err := OpenFromZip(filepath.Join(dir, filename), "")
+				if err != nil {
+					t.Fatalf("Error when opening zip file: %v", err)
+				}
+				defer r.Close()
+				var shapes []Shape
+				for r.Next() {
+					_, shape := r.Shape()
+					shapes = append(shapes, shape)
+				}
+				if err := r.Err(); err != nil {
+					t.Errorf("Error when iterating over the shapes: %v", err)
+				}
+				return shapes
+			}
+			testshapeIdentity(t, prefix, getter)
+		}
+	}
+}
+
+func TestOpenFromZipAttributes(t *testing.T) {
+	prefix := "test_files/polyline"
+	lr, err := Open(prefix + ".shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lr.Close()
+	for _, method := range []uint16{zip.Store, zip.Deflate} {
+		dir, filename := createTempZIPWithMethod(prefix, method, t)
+		defer os.RemoveAll(dir)
+		zr, err := OpenFromZip(filepath.Join(dir, filename), "polyline.shp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		if got, want := zr.AttributeCount(), lr.AttributeCount(); got != want {
+			t.Fatalf("method %d: got %d attribute rows, want %d", method, got, want)
+		}
+		// read backwards to make sure that seeking works
+		for row := zr.AttributeCount() - 1; row >= 0; row-- {
+			for field := range lr.Fields() {
+				if got, want := zr.ReadAttribute(row, field), lr.ReadAttribute(row, field); got != want {
+					t.Errorf("method %d: row %d, field %d: got %q, want %q", method, row, field, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestZipCaseInsensitiveLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-shp-test")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	zipName := filepath.Join(dir, "mixed.zip")
+	w, err := os.Create(zipName)
+	if err != nil {
+		t.Fatalf("Could not create temporary zip file: %v", err)
+	}
+	zw := zip.NewWriter(w)
+	for src, tgt := range map[string]string{
+		"test_files/point.shp":    "data/POINTS.SHP",
+		"test_files/point.shx":    "data/points.Shx",
+		"test_files/point.dbf":    "data/points.dbf",
+		"test_files/polygon.shp":  "__MACOSX/data/._POINTS.SHP",
+		"test_files/polyline.shp": "data/.hidden.shp",
+	} {
+		compressFileToZIP(zw, src, tgt, zip.Deflate, t)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Could not close the written zip: %v", err)
+	}
+	w.Close()
+
+	layers, err := LayersInZip(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || layers[0].Name != "data/POINTS.SHP" {
+		t.Fatalf("got layers %+v, want only data/POINTS.SHP", layers)
+	}
+	for _, ext := range []string{".shp", ".SHX", ".dbf"} {
+		if !layers[0].Has(ext) {
+			t.Errorf("layer is missing %s file", ext)
+		}
+	}
+	if layers[0].Has(".prj") {
+		t.Error("layer has unexpected .prj file")
+	}
+	fi, err := os.Stat("test_files/point.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := layers[0].Files[".dbf"]; f.Name != "data/points.dbf" || f.Size != fi.Size() {
+		t.Errorf("got .dbf file %+v, want data/points.dbf with size %d", f, fi.Size())
+	}
+
+	names, err := ShapesInZip(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "data/POINTS.SHP" {
+		t.Fatalf("got shapes %v, want [data/POINTS.SHP]", names)
+	}
+	zr, err := OpenZip(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.Fields()) != 1 {
+		t.Fatalf("got %d fields, want 1", len(zr.Fields()))
+	}
+	n := 0
+	for zr.Next() {
+		n++
+	}
+	if zr.Err() != nil || n != 3 {
+		t.Fatalf("read %d shapes with error %v, want 3", n, zr.Err())
+	}
+	r, err := OpenFromZip(zipName, "data/points.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.AttributeCount() != 3 {
+		t.Fatalf("got %d attribute rows, want 3", r.AttributeCount())
+	}
+}
+
 func unzipToTempDir(t *testing.T, p string) string {
 	td, err := ioutil.TempDir("", "")
 	if err != nil {